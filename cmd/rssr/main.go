@@ -1,9 +1,16 @@
 package main
 
 import (
+	"os"
+
+	"github.com/emilosman/rssr/internal/cli"
 	"github.com/emilosman/rssr/internal/tui"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	tui.BuildApp()
 }
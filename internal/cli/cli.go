@@ -0,0 +1,168 @@
+// Package cli runs rssr subcommands without starting the TUI.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/emilosman/rssr/internal/rss"
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *env, args []string) error
+}
+
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var ErrUsage = errors.New("wrong arguments")
+
+// commands are listed in help in this order
+var commands []command
+
+func init() {
+	commands = []command{
+		{"update", "[-category NAME] [-force]", "refresh feeds and save them", runUpdate},
+		{"list", "feeds|items [-unread] [-category NAME] [-search TEXT] [-json]", "print feeds or items", runList},
+		{"mark-read", "GUID|FEED_URL|CATEGORY", "mark an item, a feed or a category as read", runMarkRead},
+		{"open", "GUID", "open an item in the browser and mark it read", runOpen},
+		{"add", "URL -category NAME [-title TITLE] [-no-discover]", "add a feed to urls.yaml", runAdd},
+		{"discover", "URL", "list the feeds of a web page", runDiscover},
+		{"remove", "URL", "remove a feed from urls.yaml", runRemove},
+		{"move", "URL -category NAME", "move a feed to another category in urls.yaml", runMove},
+		{"import-opml", "FILE", "add the feeds of an OPML file to urls.yaml", runImportOPML},
+		{"export-opml", "[FILE]", "write all feeds as OPML, to stdout without FILE", runExportOPML},
+		{"import-newsboat", "[FILE]", "add the feeds of a newsboat urls file to urls.yaml", runImportNewsboat},
+		{"import-newsboat-cache", "[-star FLAGS] [-no-update] [FILE]", "copy read and starred state from a newsboat cache.db", runImportNewsboatCache},
+		{"gc", "[-dry-run]", "remove old items by the retention settings in config.yaml", runGC},
+		{"help", "", "show this help", runHelp},
+	}
+}
+
+// Run runs the subcommand in args and returns the exit code.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 {
+		runHelp(c, nil)
+		return 2
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+
+		err := cmd.run(c, args[1:])
+		if errors.Is(err, ErrUsage) {
+			fmt.Fprintf(stderr, "usage: rssr %s %s\n", cmd.name, cmd.args)
+			return 2
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "rssr %s: %v\n", cmd.name, err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "rssr: unknown command %q\n", args[0])
+	runHelp(&env{stdout: stderr}, nil)
+	return 2
+}
+
+func runHelp(c *env, args []string) error {
+	fmt.Fprintln(c.stdout, "usage: rssr [command]")
+	fmt.Fprintln(c.stdout, "\nWithout a command the reader is started.\n\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stdout, "  %-28s %s\n", cmd.name+" "+cmd.args, cmd.summary)
+	}
+	return nil
+}
+
+// parseFlags parses the flags of a command and checks the number of
+// remaining arguments. Flags may come before or after the arguments, which
+// are returned in order.
+func parseFlags(fset *flag.FlagSet, args []string, minArgs, maxArgs int) ([]string, error) {
+	fset.SetOutput(io.Discard)
+
+	var rest []string
+	for {
+		if err := fset.Parse(args); err != nil {
+			return nil, ErrUsage
+		}
+		if fset.NArg() == 0 {
+			break
+		}
+		rest = append(rest, fset.Arg(0))
+		args = fset.Args()[1:]
+	}
+
+	if n := len(rest); n < minArgs || n > maxArgs {
+		return nil, ErrUsage
+	}
+	return rest, nil
+}
+
+// arg returns the argument at i, or "" when there are fewer
+func arg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+// loadList loads the list for commands that read it. A missing cache file
+// only means nothing was fetched yet.
+func loadList() (*rss.List, error) {
+	return loadListWith(loadConfig())
+}
+
+// loadListWith loads the list from the storage cfg sets, for commands that
+// also need the config.
+func loadListWith(cfg *rss.Config) (*rss.List, error) {
+	dir, err := rss.UrlsFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := rss.OpenStorage(cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := rss.LoadListFrom(os.DirFS(dir), storage)
+	if errors.Is(err, rss.ErrDataFileRestored) {
+		fmt.Fprintln(os.Stderr, err)
+		return l, nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return l, nil
+}
+
+// loadConfig loads config.yaml. An invalid config is reported and the
+// defaults are used, as in the TUI.
+func loadConfig() *rss.Config {
+	dir, err := rss.ConfigFilePath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return rss.DefaultConfig()
+	}
+
+	cfg, err := rss.LoadConfig(os.DirFS(dir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return cfg
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCli(t *testing.T) {
+	t.Run("Should list commands in help", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		code := Run([]string{"help"}, strings.NewReader(""), &stdout, &stderr)
+		if code != 0 {
+			t.Errorf("got exit code %d, want 0", code)
+		}
+
+		for _, cmd := range commands {
+			if !strings.Contains(stdout.String(), cmd.name) {
+				t.Errorf("Help does not list %s", cmd.name)
+			}
+		}
+	})
+
+	t.Run("Should reject unknown command", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		code := Run([]string{"nope"}, strings.NewReader(""), &stdout, &stderr)
+		if code != 2 {
+			t.Errorf("got exit code %d, want 2", code)
+		}
+		if !strings.Contains(stderr.String(), "unknown command") {
+			t.Errorf("Unexpected output: %s", stderr.String())
+		}
+	})
+
+	t.Run("Should print usage on wrong arguments", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		code := Run([]string{"import-opml"}, strings.NewReader(""), &stdout, &stderr)
+		if code != 2 {
+			t.Errorf("got exit code %d, want 2", code)
+		}
+		if !strings.Contains(stderr.String(), "usage: rssr import-opml FILE") {
+			t.Errorf("Unexpected output: %s", stderr.String())
+		}
+	})
+}
@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const feedXML = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Test feed</title>
+    <link>https://example.com/</link>
+    <item>
+      <title>First</title>
+      <link>https://example.com/1</link>
+      <guid>guid-1</guid>
+    </item>
+    <item>
+      <title>Second</title>
+      <link>https://example.com/2</link>
+      <guid>guid-2</guid>
+    </item>
+  </channel>
+</rss>`
+
+// setupDirs points the config and cache dirs at a temp dir and writes
+// urls.yaml with the test server under the "news" category.
+func setupDirs(t *testing.T) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(feedXML))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+
+	configDir := filepath.Join(dir, "config", "rssr")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	urls := "news:\n  - " + server.URL + "\n"
+	if err := os.WriteFile(filepath.Join(configDir, "urls.yaml"), []byte(urls), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func run(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := Run(args, strings.NewReader(""), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestCommands(t *testing.T) {
+	t.Run("Should update, list and mark items read", func(t *testing.T) {
+		setupDirs(t)
+
+		out, errOut, code := run(t, "update")
+		if code != 0 {
+			t.Fatalf("update failed with %d: %s", code, errOut)
+		}
+		if !strings.Contains(out, "1 updated") {
+			t.Errorf("Unexpected output: %s", out)
+		}
+
+		out, _, code = run(t, "list", "items", "-json")
+		if code != 0 {
+			t.Fatalf("list failed with %d", code)
+		}
+
+		var items []itemJSON
+		if err := json.Unmarshal([]byte(out), &items); err != nil {
+			t.Fatalf("Invalid JSON: %q", err)
+		}
+		if len(items) != 2 || items[0].Read {
+			t.Fatalf("Unexpected items: %+v", items)
+		}
+
+		if _, errOut, code := run(t, "mark-read", "guid-1"); code != 0 {
+			t.Fatalf("mark-read failed with %d: %s", code, errOut)
+		}
+
+		out, _, _ = run(t, "list", "items", "--unread")
+		if strings.Contains(out, "guid-1") || !strings.Contains(out, "guid-2") {
+			t.Errorf("Item not marked read: %s", out)
+		}
+
+		if _, _, code := run(t, "mark-read", "news"); code != 0 {
+			t.Fatalf("mark-read category failed with %d", code)
+		}
+
+		out, _, _ = run(t, "list", "feeds", "-unread", "-category", "news")
+		if strings.TrimSpace(out) != "" {
+			t.Errorf("Category not marked read: %s", out)
+		}
+	})
+
+	t.Run("Should fail on unknown targets", func(t *testing.T) {
+		setupDirs(t)
+
+		if _, _, code := run(t, "mark-read", "nope"); code != 1 {
+			t.Errorf("got exit code %d, want 1", code)
+		}
+		if _, _, code := run(t, "open", "nope"); code != 1 {
+			t.Errorf("got exit code %d, want 1", code)
+		}
+		if _, _, code := run(t, "list", "nope"); code != 2 {
+			t.Errorf("got exit code %d, want 2", code)
+		}
+	})
+
+	t.Run("Should add, move and remove feeds", func(t *testing.T) {
+		setupDirs(t)
+
+		if _, errOut, code := run(t, "add", "https://example.com/feed", "-category", "blogs", "-title", "Example", "-no-discover"); code != 0 {
+			t.Fatalf("add failed with %d: %s", code, errOut)
+		}
+		if _, _, code := run(t, "add", "https://example.com/feed", "-category", "blogs", "-no-discover"); code != 1 {
+			t.Errorf("Adding twice should fail, got %d", code)
+		}
+		if _, _, code := run(t, "add", "https://example.com/feed"); code != 2 {
+			t.Errorf("Add without category should print usage, got %d", code)
+		}
+
+		if _, errOut, code := run(t, "move", "https://example.com/feed", "-category", "news"); code != 0 {
+			t.Fatalf("move failed with %d: %s", code, errOut)
+		}
+
+		out, _, _ := run(t, "list", "feeds", "-category", "news")
+		if !strings.Contains(out, "Example") {
+			t.Errorf("Feed not moved: %s", out)
+		}
+
+		if _, errOut, code := run(t, "remove", "https://example.com/feed"); code != 0 {
+			t.Fatalf("remove failed with %d: %s", code, errOut)
+		}
+
+		out, _, _ = run(t, "list", "feeds")
+		if strings.Contains(out, "https://example.com/feed") {
+			t.Errorf("Feed not removed: %s", out)
+		}
+	})
+
+	t.Run("Should move data.json to sqlite and search it", func(t *testing.T) {
+		setupDirs(t)
+
+		if _, errOut, code := run(t, "update"); code != 0 {
+			t.Fatalf("update failed with %d: %s", code, errOut)
+		}
+		if _, errOut, code := run(t, "mark-read", "guid-1"); code != 0 {
+			t.Fatalf("mark-read failed with %d: %s", code, errOut)
+		}
+
+		configFile := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "rssr", "config.yaml")
+		if err := os.WriteFile(configFile, []byte("storage: sqlite\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		out, errOut, code := run(t, "list", "items", "-unread")
+		if code != 0 {
+			t.Fatalf("list failed with %d: %s", code, errOut)
+		}
+		if strings.Contains(out, "guid-1") || !strings.Contains(out, "guid-2") {
+			t.Errorf("Read state not moved: %s", out)
+		}
+
+		out, _, _ = run(t, "list", "items", "-search", "sec")
+		if strings.Contains(out, "guid-1") || !strings.Contains(out, "guid-2") {
+			t.Errorf("Unexpected search result: %s", out)
+		}
+
+		if _, errOut, code := run(t, "mark-read", "news"); code != 0 {
+			t.Fatalf("mark-read category failed with %d: %s", code, errOut)
+		}
+		out, _, _ = run(t, "list", "feeds", "-unread")
+		if strings.TrimSpace(out) != "" {
+			t.Errorf("Category not marked read: %s", out)
+		}
+	})
+
+	t.Run("Should prune items by the retention settings", func(t *testing.T) {
+		setupDirs(t)
+
+		if _, errOut, code := run(t, "update"); code != 0 {
+			t.Fatalf("update failed with %d: %s", code, errOut)
+		}
+		if _, _, code := run(t, "gc"); code == 0 {
+			t.Error("gc should fail without retention settings")
+		}
+
+		configFile := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "rssr", "config.yaml")
+		if err := os.WriteFile(configFile, []byte("retention:\n  max_items: 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		out, errOut, code := run(t, "gc", "-dry-run")
+		if code != 0 || !strings.Contains(out, "1 items pruned from 1 feeds") {
+			t.Fatalf("gc -dry-run failed with %d: %s%s", code, out, errOut)
+		}
+		if out, _, _ := run(t, "list", "items"); !strings.Contains(out, "guid-2") {
+			t.Errorf("-dry-run should not save: %s", out)
+		}
+
+		if out, errOut, code := run(t, "gc"); code != 0 || !strings.Contains(out, "1 items pruned") {
+			t.Fatalf("gc failed with %d: %s%s", code, out, errOut)
+		}
+
+		// The feed still lists the pruned item
+		if _, errOut, code := run(t, "update", "-force"); code != 0 {
+			t.Fatalf("update failed with %d: %s", code, errOut)
+		}
+		out, _, _ = run(t, "list", "items")
+		if !strings.Contains(out, "guid-1") || strings.Contains(out, "guid-2") {
+			t.Errorf("Pruned item came back: %s", out)
+		}
+	})
+	t.Run("Should report an invalid config and use the defaults", func(t *testing.T) {
+		setupDirs(t)
+
+		configFile := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "rssr", "config.yaml")
+		if err := os.WriteFile(configFile, []byte("storage: nope\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// The config error goes to the process stderr
+		stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stderr.Close()
+		old := os.Stderr
+		os.Stderr = stderr
+		t.Cleanup(func() { os.Stderr = old })
+
+		if _, errOut, code := run(t, "update"); code != 0 {
+			t.Fatalf("update failed with %d: %s", code, errOut)
+		}
+		if out, _, code := run(t, "list", "items"); code != 0 || !strings.Contains(out, "guid-1") {
+			t.Fatalf("list failed with %d: %s", code, out)
+		}
+
+		reported, err := os.ReadFile(stderr.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(reported), "config") {
+			t.Errorf("Config error not reported: %q", reported)
+		}
+	})
+}
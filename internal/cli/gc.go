@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/emilosman/rssr/internal/rss"
+)
+
+func runGC(c *env, args []string) error {
+	fset := flag.NewFlagSet("gc", flag.ContinueOnError)
+	dryRun := fset.Bool("dry-run", false, "report what would be pruned without saving")
+	if _, err := parseFlags(fset, args, 0, 0); err != nil {
+		return err
+	}
+
+	cfg := loadConfig()
+	if !cfg.Retention.Enabled() {
+		return errors.New("no retention set in config.yaml, nothing to prune")
+	}
+
+	l, err := loadListWith(cfg)
+	if err != nil {
+		return err
+	}
+
+	pruned := l.Prune(cfg.Retention, time.Now())
+	total := 0
+	for _, url := range slices.Sorted(maps.Keys(pruned)) {
+		fmt.Fprintf(c.stdout, "%s: %d\n", url, pruned[url])
+		total += pruned[url]
+	}
+
+	if !*dryRun && total > 0 {
+		if err := rss.SaveList(l); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(c.stdout, "%d items pruned from %d feeds\n", total, len(pruned))
+	return nil
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/emilosman/rssr/internal/rss"
+)
+
+func runMarkRead(c *env, args []string) error {
+	fset := flag.NewFlagSet("mark-read", flag.ContinueOnError)
+	rest, err := parseFlags(fset, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	target := rest[0]
+	l.ReindexList()
+
+	switch {
+	case l.ItemIndex[target] != nil:
+		l.ItemIndex[target].MarkRead()
+		fmt.Fprintf(c.stdout, "Marked item as read\n")
+	case l.FeedIndex[target] != nil && target != "Bookmarks":
+		feed := l.FeedIndex[target]
+		feed.MarkAllItemsRead()
+		fmt.Fprintf(c.stdout, "Marked %s as read\n", feed.Name())
+	case len(l.CategoryIndex[target]) > 0:
+		rss.MarkFeedsAsRead(l.CategoryIndex[target]...)
+		fmt.Fprintf(c.stdout, "Marked %d feeds in %s as read\n", len(l.CategoryIndex[target]), target)
+	default:
+		return fmt.Errorf("no item, feed or category %q", target)
+	}
+
+	return rss.SaveList(l)
+}
+
+func runOpen(c *env, args []string) error {
+	fset := flag.NewFlagSet("open", flag.ContinueOnError)
+	rest, err := parseFlags(fset, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	cfg := loadConfig()
+	l, err := loadListWith(cfg)
+	if err != nil {
+		return err
+	}
+
+	l.ReindexList()
+	item := l.ItemIndex[rest[0]]
+	if item == nil {
+		return fmt.Errorf("no item %q", rest[0])
+	}
+
+	cmd, terminal, err := cfg.OpenCommand(rss.Link{Url: item.Link(), Title: item.Item.Title, Feed: l.FeedOf(item)})
+	if err != nil {
+		return err
+	}
+
+	if terminal {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = c.stdin, c.stdout, c.stderr
+		err = cmd.Run()
+	} else {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return err
+	}
+
+	item.MarkRead()
+	return rss.SaveList(l)
+}
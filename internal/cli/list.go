@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/emilosman/rssr/internal/rss"
+)
+
+type feedJSON struct {
+	Url         string    `json:"url"`
+	Title       string    `json:"title"`
+	Category    string    `json:"category"`
+	Categories  []string  `json:"categories"`
+	Unread      int       `json:"unread"`
+	Items       int       `json:"items"`
+	Error       string    `json:"error,omitempty"`
+	LastRefresh time.Time `json:"last_refresh,omitzero"`
+}
+
+type itemJSON struct {
+	GUID      string     `json:"guid"`
+	Title     string     `json:"title"`
+	Link      string     `json:"link"`
+	Feed      string     `json:"feed"`
+	FeedUrl   string     `json:"feed_url"`
+	Published *time.Time `json:"published,omitempty"`
+	Read      bool       `json:"read"`
+	Bookmark  bool       `json:"bookmark"`
+}
+
+func runList(c *env, args []string) error {
+	fset := flag.NewFlagSet("list", flag.ContinueOnError)
+	unread := fset.Bool("unread", false, "only feeds or items with unread items")
+	category := fset.String("category", "", "only this category")
+	search := fset.String("search", "", "only items with words starting with every word of TEXT")
+	asJSON := fset.Bool("json", false, "print JSON")
+	rest, err := parseFlags(fset, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	feeds := l.Subscriptions()
+	if *category != "" {
+		feeds, err = l.GetCategory(*category)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch rest[0] {
+	case "feeds":
+		counts, err := l.Storage.UnreadCounts()
+		if err != nil {
+			return err
+		}
+		return listFeeds(c.stdout, feeds, counts, *unread, *asJSON)
+	case "items":
+		var found map[string]bool
+		if *search != "" {
+			guids, err := l.Storage.Search(*search)
+			if err != nil {
+				return err
+			}
+			found = make(map[string]bool, len(guids))
+			for _, guid := range guids {
+				found[guid] = true
+			}
+		}
+		return listItems(c.stdout, feeds, found, *unread, *asJSON)
+	default:
+		return ErrUsage
+	}
+}
+
+// listFeeds prints the feeds with their unread counts from the storage.
+func listFeeds(w io.Writer, feeds []*rss.RssFeed, counts map[string]int, unread, asJSON bool) error {
+	out := []feedJSON{}
+	for _, feed := range feeds {
+		if unread && counts[feed.Url] == 0 {
+			continue
+		}
+
+		out = append(out, feedJSON{
+			Url:         feed.Url,
+			Title:       feed.Name(),
+			Category:    feed.Category,
+			Categories:  feed.Categories,
+			Unread:      counts[feed.Url],
+			Items:       len(feed.RssItems),
+			Error:       feed.Error,
+			LastRefresh: feed.LastRefresh,
+		})
+	}
+
+	if asJSON {
+		return writeJSON(w, out)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, f := range out {
+		fmt.Fprintf(tw, "%s\t%d/%d\t%s\t%s\n", f.Category, f.Unread, f.Items, f.Title, f.Url)
+	}
+	return tw.Flush()
+}
+
+// listItems prints the items of the feeds, only the ones in found when it
+// is not nil.
+func listItems(w io.Writer, feeds []*rss.RssFeed, found map[string]bool, unread, asJSON bool) error {
+	out := []itemJSON{}
+	for _, feed := range feeds {
+		for _, item := range feed.RssItems {
+			if item.Item == nil || (unread && item.Read) {
+				continue
+			}
+			if found != nil && !found[item.GUID()] {
+				continue
+			}
+
+			out = append(out, itemJSON{
+				GUID:      item.GUID(),
+				Title:     item.Item.Title,
+				Link:      item.Link(),
+				Feed:      feed.Name(),
+				FeedUrl:   feed.Url,
+				Published: item.Timestamp(),
+				Read:      item.Read,
+				Bookmark:  item.Bookmark,
+			})
+		}
+	}
+
+	if asJSON {
+		return writeJSON(w, out)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, i := range out {
+		state := " "
+		if !i.Read {
+			state = "+"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", state, i.Feed, i.Title, i.GUID)
+	}
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
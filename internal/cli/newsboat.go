@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/emilosman/rssr/internal/rss"
+)
+
+func runImportNewsboat(c *env, args []string) error {
+	fset := flag.NewFlagSet("import-newsboat", flag.ContinueOnError)
+	rest, err := parseFlags(fset, args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	path := arg(rest, 0)
+	if path == "" {
+		path = rss.NewsboatUrlsPath()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	subs, warnings, err := rss.ReadNewsboatUrls(f)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range warnings {
+		fmt.Fprintf(c.stderr, "%s: %s\n", path, w)
+	}
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	added, err := rss.ImportSubscriptions(l, subs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Imported %d of %d feeds, %d lines skipped\n", added, len(subs), len(warnings))
+	return nil
+}
+
+func runImportNewsboatCache(c *env, args []string) error {
+	fset := flag.NewFlagSet("import-newsboat-cache", flag.ContinueOnError)
+	star := fset.String("star", rss.NewsboatStarFlags, "newsboat flags that mark an item as starred")
+	noUpdate := fset.Bool("no-update", false, "do not refresh feeds before importing")
+	rest, err := parseFlags(fset, args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	path := arg(rest, 0)
+	if path == "" {
+		path = rss.NewsboatCachePath()
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	cfg := loadConfig()
+	l, err := loadListWith(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Only items that were fetched can be matched
+	if !*noUpdate {
+		fmt.Fprintln(c.stdout, "Refreshing feeds...")
+		results, err := l.UpdateAllFeedsContext(context.Background(), cfg.UpdateOptions())
+		if err != nil {
+			return err
+		}
+		for res := range results {
+			if res.Err != nil && res.Feed.Category != "" {
+				fmt.Fprintf(c.stderr, "%s: %v\n", res.Feed.Url, res.Err)
+			}
+		}
+	}
+
+	res, err := rss.ImportNewsboatCache(l, path, *star, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := rss.SaveList(l); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Matched %d items: %d marked read, %d starred. %d items not found\n",
+		res.Matched, res.Read, res.Starred, res.Unmatched)
+	return nil
+}
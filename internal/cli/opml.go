@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/emilosman/rssr/internal/rss"
+)
+
+func runImportOPML(c *env, args []string) error {
+	fset := flag.NewFlagSet("import-opml", flag.ContinueOnError)
+	rest, err := parseFlags(fset, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(rest[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	subs, err := rss.ReadOPML(f)
+	if err != nil {
+		return err
+	}
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	added, err := rss.ImportSubscriptions(l, subs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Imported %d of %d feeds\n", added, len(subs))
+	return nil
+}
+
+func runExportOPML(c *env, args []string) error {
+	fset := flag.NewFlagSet("export-opml", flag.ContinueOnError)
+	rest, err := parseFlags(fset, args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	path := arg(rest, 0)
+	if path == "" || path == "-" {
+		return l.WriteOPML(c.stdout, time.Now())
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := l.WriteOPML(f, time.Now()); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Exported feeds to %s\n", path)
+	return nil
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/emilosman/rssr/internal/rss"
+)
+
+func runAdd(c *env, args []string) error {
+	fset := flag.NewFlagSet("add", flag.ContinueOnError)
+	category := fset.String("category", "", "category to add the feed to")
+	title := fset.String("title", "", "title shown instead of the feed title")
+	noDiscover := fset.Bool("no-discover", false, "add the URL as is, without looking for the feed of a web page")
+	rest, err := parseFlags(fset, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if *category == "" {
+		return ErrUsage
+	}
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	u := rest[0]
+	if !*noDiscover {
+		if u, err = discoverOne(c, u); err != nil {
+			return err
+		}
+	}
+
+	s := rss.FeedSettings{Url: u, Title: *title}
+	if _, err := rss.AddSubscription(l, *category, s); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Added %s to %s\n", s.Url, *category)
+	return nil
+}
+
+func runRemove(c *env, args []string) error {
+	fset := flag.NewFlagSet("remove", flag.ContinueOnError)
+	rest, err := parseFlags(fset, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	bookmarks := 0
+	if feed := l.FeedIndex[rest[0]]; feed != nil {
+		bookmarks = feed.BookmarkCount()
+	}
+
+	if err := rss.RemoveSubscription(l, rest[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Removed %s\n", rest[0])
+	if bookmarks > 0 {
+		fmt.Fprintf(c.stdout, "Removed its %d bookmarks\n", bookmarks)
+	}
+	return rss.SaveList(l)
+}
+
+func runMove(c *env, args []string) error {
+	fset := flag.NewFlagSet("move", flag.ContinueOnError)
+	category := fset.String("category", "", "category to move the feed to")
+	rest, err := parseFlags(fset, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if *category == "" {
+		return ErrUsage
+	}
+
+	l, err := loadList()
+	if err != nil {
+		return err
+	}
+
+	if err := rss.MoveSubscription(l, rest[0], *category); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Moved %s to %s\n", rest[0], *category)
+	return nil
+}
+
+func runDiscover(c *env, args []string) error {
+	fset := flag.NewFlagSet("discover", flag.ContinueOnError)
+	rest, err := parseFlags(fset, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rss.DefaultUpdateOptions.Timeout)
+	defer cancel()
+
+	found, err := rss.Discover(ctx, rest[0])
+	if err != nil {
+		return err
+	}
+
+	for _, f := range found {
+		fmt.Fprintf(c.stdout, "%s\t%s\n", f.Url, f.Title)
+	}
+	return nil
+}
+
+// discoverOne returns the feed for a URL that may be a web page. When the
+// page has several feeds the user picks one. A URL that cannot be checked
+// is used as is.
+func discoverOne(c *env, u string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rss.DefaultUpdateOptions.Timeout)
+	defer cancel()
+
+	found, err := rss.Discover(ctx, u)
+	switch {
+	case errors.Is(err, rss.ErrNoFeedFound):
+		return "", err
+	case err != nil:
+		fmt.Fprintf(c.stderr, "Could not check %s, adding it as is: %v\n", u, err)
+		return u, nil
+	case len(found) == 1:
+		if found[0].Url != u {
+			fmt.Fprintf(c.stdout, "Found feed %s\n", found[0].Url)
+		}
+		return found[0].Url, nil
+	}
+
+	fmt.Fprintf(c.stdout, "%s has %d feeds:\n", u, len(found))
+	for i, f := range found {
+		fmt.Fprintf(c.stdout, "  %d) %s %s\n", i+1, f.Url, f.Title)
+	}
+	fmt.Fprintf(c.stdout, "Feed to add [1-%d]: ", len(found))
+
+	var choice int
+	if _, err := fmt.Fscanln(c.stdin, &choice); err != nil || choice < 1 || choice > len(found) {
+		return "", fmt.Errorf("no feed chosen, add one of the URLs above")
+	}
+	return found[choice-1].Url, nil
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/emilosman/rssr/internal/rss"
+)
+
+func runUpdate(c *env, args []string) error {
+	fset := flag.NewFlagSet("update", flag.ContinueOnError)
+	category := fset.String("category", "", "only refresh feeds in this category")
+	force := fset.Bool("force", false, "refresh feeds that are not due or waiting to retry")
+	if _, err := parseFlags(fset, args, 0, 0); err != nil {
+		return err
+	}
+
+	cfg := loadConfig()
+	l, err := loadListWith(cfg)
+	if err != nil {
+		return err
+	}
+
+	feeds := l.Subscriptions()
+	if *category != "" {
+		feeds, err = l.GetCategory(*category)
+		if err != nil {
+			return err
+		}
+		if len(feeds) == 0 {
+			return fmt.Errorf("no feeds in category %q", *category)
+		}
+	}
+
+	opts := cfg.UpdateOptions()
+	opts.Force = *force
+
+	results, err := rss.UpdateFeedsContext(context.Background(), opts, feeds...)
+	if err != nil {
+		return err
+	}
+
+	var updated, unchanged, skipped, failed int
+	for res := range results {
+		switch {
+		case res.Err != nil:
+			failed++
+			fmt.Fprintf(c.stderr, "%s: %v\n", res.Feed.Url, res.Err)
+		case res.Skipped, res.Cancelled:
+			skipped++
+		case res.NotModified:
+			unchanged++
+		default:
+			updated++
+		}
+	}
+
+	if err := rss.SaveList(l); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "%d updated, %d not modified, %d skipped, %d failed\n", updated, unchanged, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d feeds failed", failed)
+	}
+	return nil
+}
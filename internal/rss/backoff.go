@@ -0,0 +1,93 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = 6 * time.Hour
+	// Upper bound for Retry-After, so a misconfigured server can not park a
+	// feed for weeks.
+	retryAfterMax = 24 * time.Hour
+)
+
+// HTTPError is returned for non-2xx responses. RetryAfter is set when the
+// server sent a Retry-After header.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration
+}
+
+func (e HTTPError) Error() string {
+	return fmt.Sprintf("http error: %s", e.Status)
+}
+
+// scheduleRetry counts the failure and sets the earliest time the feed may
+// be fetched again. Retry-After wins over the computed delay.
+func (f *RssFeed) scheduleRetry(err error, now time.Time) {
+	f.Failures++
+
+	delay := retryDelay(f.Failures)
+
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+		delay = min(httpErr.RetryAfter, retryAfterMax)
+	}
+
+	f.RetryAt = now.Add(delay)
+}
+
+// retryDelay doubles with every failure up to retryMaxDelay. Half of the
+// delay is random so feeds that failed together do not retry together.
+func retryDelay(failures int) time.Duration {
+	delay := retryMaxDelay
+	if failures < 20 {
+		delay = min(retryBaseDelay<<(max(failures, 1)-1), retryMaxDelay)
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// waiting reports whether the feed is still backing off from a failure
+func (f *RssFeed) waiting(now time.Time) bool {
+	return now.Before(f.RetryAt)
+}
+
+func (f *RssFeed) nextRetry(now time.Time) string {
+	if !f.waiting(now) {
+		return ""
+	}
+
+	if f.RetryAt.Sub(now) < 24*time.Hour {
+		return f.RetryAt.Local().Format("15:04")
+	}
+	return f.RetryAt.Local().Format("Jan 2 15:04")
+}
+
+// parseRetryAfter reads both forms of the header, delay in seconds and an
+// HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+
+	return 0
+}
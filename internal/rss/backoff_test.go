@@ -0,0 +1,163 @@
+package rss
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ServerRetryAfter(t *testing.T, status int, retryAfter string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", retryAfter)
+		w.WriteHeader(status)
+	}))
+	return server
+}
+
+func TestBackoff(t *testing.T) {
+	t.Run("Should parse Retry-After", func(t *testing.T) {
+		now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		tests := []struct {
+			name     string
+			value    string
+			expected time.Duration
+		}{
+			{"seconds", "120", 2 * time.Minute},
+			{"http date", "Wed, 01 Jan 2025 12:30:00 GMT", 30 * time.Minute},
+			{"date in the past", "Wed, 01 Jan 2025 11:00:00 GMT", 0},
+			{"negative seconds", "-5", 0},
+			{"empty", "", 0},
+			{"invalid", "soon", 0},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got := parseRetryAfter(tt.value, now)
+				if got != tt.expected {
+					t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.expected)
+				}
+			})
+		}
+	})
+
+	t.Run("Should grow retry delay with failures", func(t *testing.T) {
+		tests := []struct {
+			failures int
+			max      time.Duration
+		}{
+			{1, time.Minute},
+			{2, 2 * time.Minute},
+			{3, 4 * time.Minute},
+			{10, retryMaxDelay},
+			{100, retryMaxDelay},
+		}
+
+		for _, tt := range tests {
+			for range 20 {
+				got := retryDelay(tt.failures)
+				if got < tt.max/2 || got > tt.max {
+					t.Errorf("retryDelay(%d) = %s, want between %s and %s", tt.failures, got, tt.max/2, tt.max)
+				}
+			}
+		}
+	})
+
+	t.Run("Should schedule retry after failure", func(t *testing.T) {
+		server := ServerNotFound(t)
+		defer server.Close()
+
+		rssFeed := RssFeed{Url: server.URL}
+		now := time.Now()
+
+		for i := 1; i <= 3; i++ {
+			err := rssFeed.GetFeed()
+			if err == nil {
+				t.Fatal("Should return error on server error")
+			}
+
+			if rssFeed.Failures != i {
+				t.Errorf("Wrong number of failures, wanted %d, got %d", i, rssFeed.Failures)
+			}
+		}
+
+		if !rssFeed.RetryAt.After(now) {
+			t.Error("Retry time not scheduled")
+		}
+
+		if !strings.Contains(rssFeed.Latest(), "retry") {
+			t.Errorf("Latest should show next retry, got %q", rssFeed.Latest())
+		}
+	})
+
+	t.Run("Should honor Retry-After", func(t *testing.T) {
+		server := ServerRetryAfter(t, http.StatusTooManyRequests, "3600")
+		defer server.Close()
+
+		rssFeed := RssFeed{Url: server.URL}
+		now := time.Now()
+
+		err := rssFeed.GetFeed()
+
+		var httpErr HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("Expected HTTP error, got %q", err)
+		}
+
+		if httpErr.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Wrong status code, got %d", httpErr.StatusCode)
+		}
+
+		if rssFeed.RetryAt.Before(now.Add(time.Hour)) {
+			t.Errorf("Retry-After not honored, retry at %s", rssFeed.RetryAt)
+		}
+	})
+
+	t.Run("Should cap Retry-After", func(t *testing.T) {
+		rssFeed := RssFeed{}
+		now := time.Now()
+
+		rssFeed.scheduleRetry(HTTPError{RetryAfter: 30 * 24 * time.Hour}, now)
+
+		if rssFeed.RetryAt.After(now.Add(retryAfterMax)) {
+			t.Errorf("Retry-After not capped, retry at %s", rssFeed.RetryAt)
+		}
+	})
+
+	t.Run("Should reset backoff after success", func(t *testing.T) {
+		server := Server(t, testData(t, "feed.xml"))
+		defer server.Close()
+
+		rssFeed := RssFeed{
+			Url:      server.URL,
+			Error:    "Error happened",
+			Failures: 4,
+			RetryAt:  time.Now().Add(-time.Minute),
+		}
+
+		err := rssFeed.GetFeed()
+		if err != nil {
+			t.Fatalf("Error getting feed %q", err)
+		}
+
+		if rssFeed.Failures != 0 || !rssFeed.RetryAt.IsZero() || rssFeed.Error != "" {
+			t.Error("Backoff should be reset after success")
+		}
+	})
+
+	t.Run("Should not show retry when retry is due", func(t *testing.T) {
+		rssFeed := RssFeed{
+			Error:   "Error happened",
+			RetryAt: time.Now().Add(-time.Minute),
+		}
+
+		if rssFeed.Latest() != "Error happened" {
+			t.Errorf("Wrong latest, got %q", rssFeed.Latest())
+		}
+	})
+}
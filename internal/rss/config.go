@@ -0,0 +1,262 @@
+package rss
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+type Config struct {
+	// RenderMarkdown renders item content as markdown instead of showing
+	// the plain text
+	RenderMarkdown bool `yaml:"render_markdown"`
+	// Editor edits urls.yaml and config.yaml. $VISUAL and $EDITOR are used
+	// when it is empty
+	Editor string `yaml:"editor"`
+	// Browser opens links, with the URL as its last argument. The default
+	// browser of the system is used when it is empty
+	Browser string `yaml:"browser"`
+	// Openers open the links they match instead of the browser, the first
+	// match wins
+	Openers []Opener `yaml:"openers"`
+	// Rewrite changes links before they are opened, and as they are stored
+	// when on_store is set
+	Rewrite RewriteConfig `yaml:"rewrite"`
+	// WrapWidth is the column item content wraps at, 0 wraps at the width
+	// of the window
+	WrapWidth int `yaml:"wrap_width"`
+	// Theme is a built in theme or one defined in Themes
+	Theme   string           `yaml:"theme"`
+	Themes  map[string]Theme `yaml:"themes"`
+	Refresh RefreshConfig    `yaml:"refresh"`
+	// Retention limits the items kept per feed, see retention.go
+	Retention RetentionConfig `yaml:"retention"`
+	// SortCategories shows categories alphabetically instead of in the
+	// order of urls.yaml
+	SortCategories bool       `yaml:"sort_categories"`
+	Sync           SyncConfig `yaml:"sync"`
+	// Storage keeps feeds and read state in data.json or in an SQLite
+	// database, see storage.go
+	Storage string     `yaml:"storage"`
+	Keys    KeysConfig `yaml:"keys"`
+}
+
+// KeysConfig binds keys to actions in each view of the app. Keys given for
+// an action replace its default keys.
+type KeysConfig struct {
+	Feeds map[string]KeyList `yaml:"feeds"`
+	Items map[string]KeyList `yaml:"items"`
+	Item  map[string]KeyList `yaml:"item"`
+}
+
+// KeyList is a single key or a list of keys.
+type KeyList []string
+
+func (k *KeyList) UnmarshalYAML(data []byte) error {
+	var single string
+	if err := yaml.Unmarshal(data, &single); err == nil {
+		*k = KeyList{single}
+		return nil
+	}
+
+	var keys []string
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+// SyncConfig points to the server read and bookmark state is synced with.
+type SyncConfig struct {
+	Url    string `yaml:"url"`
+	ApiKey string `yaml:"api_key"`
+}
+
+// RefreshConfig sets how often feeds are refreshed in the background. The
+// most specific interval wins: the interval set on the feed in urls.yaml,
+// then feeds, then categories, then the global one. A feed in several
+// categories uses the first one with an interval. Zero disables auto
+// refresh.
+type RefreshConfig struct {
+	Interval   time.Duration            `yaml:"interval"`
+	Categories map[string]time.Duration `yaml:"categories"`
+	Feeds      map[string]time.Duration `yaml:"feeds"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		RenderMarkdown: true,
+		WrapWidth:      80,
+		Theme:          ThemeAuto,
+		Storage:        StorageJSON,
+	}
+}
+
+// LoadConfig reads config.yaml over the defaults. The defaults are returned
+// along with the error when the file is invalid.
+func LoadConfig(filesystem fs.FS) (*Config, error) {
+	c := DefaultConfig()
+
+	file, err := filesystem.Open("config.yaml")
+	if err != nil {
+		return c, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return c, err
+	}
+
+	// A file with only comments is a null document, which would zero the
+	// defaults
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err == nil && doc == nil {
+		return c, nil
+	}
+
+	if err := yaml.UnmarshalWithOptions(data, c, yaml.DisallowUnknownField()); err != nil {
+		return DefaultConfig(), fmt.Errorf("%w: %s", ErrInvalidConfig, yaml.FormatError(err, false, false))
+	}
+
+	if err := c.Validate(); err != nil {
+		return DefaultConfig(), err
+	}
+
+	return c, nil
+}
+
+// Validate checks the values that parse but make no sense, and lists all of
+// them in the error.
+func (c *Config) Validate() error {
+	var problems []string
+
+	if c.WrapWidth < 0 {
+		problems = append(problems, fmt.Sprintf("wrap_width must be 0 or more, got %d", c.WrapWidth))
+	}
+
+	if _, ok := c.Themes[c.Theme]; !ok && c.Theme != "" && !slices.Contains(BuiltinThemes, c.Theme) {
+		problems = append(problems, fmt.Sprintf("theme must be one of %s or a theme under themes, got %q", strings.Join(BuiltinThemes, ", "), c.Theme))
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Themes)) {
+		problems = append(problems, validateTheme(name, c.Themes[name])...)
+	}
+
+	if c.Refresh.Interval < 0 {
+		problems = append(problems, "refresh.interval must not be negative")
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Refresh.Categories)) {
+		if c.Refresh.Categories[name] < 0 {
+			problems = append(problems, fmt.Sprintf("refresh.categories.%s must not be negative", name))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Refresh.Feeds)) {
+		if c.Refresh.Feeds[name] < 0 {
+			problems = append(problems, fmt.Sprintf("refresh.feeds.%s must not be negative", name))
+		}
+	}
+
+	if c.Retention.MaxItems < 0 {
+		problems = append(problems, fmt.Sprintf("retention.max_items must be 0 or more, got %d", c.Retention.MaxItems))
+	}
+	if c.Retention.MaxAge < 0 {
+		problems = append(problems, "retention.max_age must not be negative")
+	}
+
+	for i, o := range c.Openers {
+		problems = append(problems, validateOpener(i, o)...)
+	}
+
+	problems = append(problems, validateRewrite(c.Rewrite)...)
+
+	if c.Storage != "" && !slices.Contains(StorageBackends, c.Storage) {
+		problems = append(problems, fmt.Sprintf("storage must be json or sqlite, got %q", c.Storage))
+	}
+
+	if c.Sync.Url != "" {
+		u, err := url.Parse(c.Sync.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("sync.url must be an http or https URL, got %q", c.Sync.Url))
+		}
+	} else if c.Sync.ApiKey != "" {
+		problems = append(problems, "sync.api_key is set but sync.url is empty")
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+}
+
+// Enabled reports whether any auto refresh interval is set. Intervals from
+// urls.yaml count as well.
+func (r RefreshConfig) Enabled(feeds ...*RssFeed) bool {
+	for _, f := range feeds {
+		if f.Settings.Interval > 0 {
+			return true
+		}
+	}
+	if r.Interval > 0 {
+		return true
+	}
+	for _, d := range r.Categories {
+		if d > 0 {
+			return true
+		}
+	}
+	for _, d := range r.Feeds {
+		if d > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RefreshConfig) IntervalFor(f *RssFeed) time.Duration {
+	if f.Settings.Interval > 0 {
+		return f.Settings.Interval
+	}
+	if d, ok := r.Feeds[f.Url]; ok {
+		return d
+	}
+	for _, category := range f.categories() {
+		if d, ok := r.Categories[category]; ok {
+			return d
+		}
+	}
+	return r.Interval
+}
+
+// DueFeeds returns the feeds whose auto refresh interval has passed since
+// they were last refreshed. Feeds backing off or asking not to be polled yet
+// are left out.
+func (r RefreshConfig) DueFeeds(feeds []*RssFeed, now time.Time) []*RssFeed {
+	var due []*RssFeed
+	for _, f := range feeds {
+		if f.Category == "" {
+			continue
+		}
+
+		interval := r.IntervalFor(f)
+		if interval <= 0 {
+			continue
+		}
+
+		if f.waiting(now) || !f.due(now) {
+			continue
+		}
+
+		if now.Sub(f.LastRefresh) >= interval {
+			due = append(due, f)
+		}
+	}
+	return due
+}
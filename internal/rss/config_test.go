@@ -0,0 +1,215 @@
+package rss
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("Should load config", func(t *testing.T) {
+		fs := fstest.MapFS{
+			"config.yaml": {Data: []byte(`
+refresh:
+  interval: 1h
+  categories:
+    golang: 30m
+  feeds:
+    https://go.dev/blog/feed.atom: 6h
+`)},
+		}
+
+		c, err := LoadConfig(fs)
+		if err != nil {
+			t.Fatalf("Error loading config: %q", err)
+		}
+
+		if c.Refresh.Interval != time.Hour {
+			t.Errorf("Wrong interval, got %s", c.Refresh.Interval)
+		}
+
+		if c.Refresh.Categories["golang"] != 30*time.Minute {
+			t.Errorf("Wrong category interval, got %s", c.Refresh.Categories["golang"])
+		}
+
+		if c.Refresh.Feeds["https://go.dev/blog/feed.atom"] != 6*time.Hour {
+			t.Errorf("Wrong feed interval, got %s", c.Refresh.Feeds["https://go.dev/blog/feed.atom"])
+		}
+	})
+
+	t.Run("Should load default config", func(t *testing.T) {
+		fs := fstest.MapFS{
+			"config.yaml": {Data: []byte(DefaultConfigFile)},
+		}
+
+		c, err := LoadConfig(fs)
+		if err != nil {
+			t.Fatalf("Error loading config: %q", err)
+		}
+
+		if c.Refresh.Enabled() {
+			t.Error("Auto refresh should be disabled by default")
+		}
+
+		if !c.RenderMarkdown || c.WrapWidth != 80 || c.Theme != ThemeAuto {
+			t.Errorf("Defaults should be used, got %+v", c)
+		}
+	})
+
+	t.Run("Should load all settings", func(t *testing.T) {
+		fs := fstest.MapFS{
+			"config.yaml": {Data: []byte(`
+render_markdown: false
+editor: code --wait
+browser: firefox --new-tab
+wrap_width: 0
+theme: light
+sync:
+  url: https://sync.example.com
+  api_key: secret
+keys:
+  items:
+    toggle_bookmark: x
+    next_unread: [j, down]
+`)},
+		}
+
+		c, err := LoadConfig(fs)
+		if err != nil {
+			t.Fatalf("Error loading config: %q", err)
+		}
+
+		if c.RenderMarkdown {
+			t.Error("Markdown rendering should be off")
+		}
+		if c.Editor != "code --wait" || c.Browser != "firefox --new-tab" {
+			t.Errorf("Wrong commands, got %q and %q", c.Editor, c.Browser)
+		}
+		if c.WrapWidth != 0 || c.Theme != ThemeLight {
+			t.Errorf("Wrong display settings, got %d and %q", c.WrapWidth, c.Theme)
+		}
+		if c.Sync.Url != "https://sync.example.com" || c.Sync.ApiKey != "secret" {
+			t.Errorf("Wrong sync settings, got %+v", c.Sync)
+		}
+		if !slices.Equal(c.Keys.Items["toggle_bookmark"], KeyList{"x"}) || !slices.Equal(c.Keys.Items["next_unread"], KeyList{"j", "down"}) {
+			t.Errorf("Wrong keys, got %v", c.Keys.Items)
+		}
+	})
+
+	t.Run("Should report every invalid value", func(t *testing.T) {
+		fs := fstest.MapFS{
+			"config.yaml": {Data: []byte(`
+wrap_width: -1
+theme: blue
+refresh:
+  interval: -1h
+sync:
+  url: alpine:8080
+storage: postgres
+retention:
+  max_items: -5
+`)},
+		}
+
+		c, err := LoadConfig(fs)
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("got %q want %q", err, ErrInvalidConfig)
+		}
+
+		for _, want := range []string{"wrap_width", `theme must be one of auto, dark, light, mono or a theme under themes, got "blue"`, "refresh.interval", "sync.url", `storage must be json or sqlite, got "postgres"`, "retention.max_items must be 0 or more, got -5"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Error should mention %s, got %q", want, err)
+			}
+		}
+
+		if c.WrapWidth != 80 {
+			t.Errorf("Defaults should be used, got %d", c.WrapWidth)
+		}
+	})
+
+	t.Run("Should report unknown settings", func(t *testing.T) {
+		fs := fstest.MapFS{
+			"config.yaml": {Data: []byte("render_markdwon: false\n")},
+		}
+
+		_, err := LoadConfig(fs)
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("got %q want %q", err, ErrInvalidConfig)
+		}
+
+		if strings.Contains(err.Error(), "\n") || !strings.Contains(err.Error(), "render_markdwon") {
+			t.Errorf("Error should name the setting on one line, got %q", err)
+		}
+	})
+
+	t.Run("Should handle invalid config", func(t *testing.T) {
+		fs := fstest.MapFS{
+			"config.yaml": {Data: []byte("refresh:\n  interval: often\n")},
+		}
+
+		c, err := LoadConfig(fs)
+		if err == nil {
+			t.Error("Should return error for invalid config")
+		}
+
+		if c == nil {
+			t.Error("Config should have been returned")
+		}
+	})
+
+	t.Run("Should use most specific refresh interval", func(t *testing.T) {
+		r := RefreshConfig{
+			Interval:   time.Hour,
+			Categories: map[string]time.Duration{"golang": 30 * time.Minute},
+			Feeds:      map[string]time.Duration{"example.com": 0},
+		}
+
+		tests := []struct {
+			name     string
+			feed     *RssFeed
+			expected time.Duration
+		}{
+			{"global", &RssFeed{Url: "other.com", Category: "jobs"}, time.Hour},
+			{"category", &RssFeed{Url: "other.com", Category: "golang"}, 30 * time.Minute},
+			{"feed", &RssFeed{Url: "example.com", Category: "golang"}, 0},
+			{"shared", &RssFeed{Url: "other.com", Category: "jobs", Categories: []string{"jobs", "golang"}}, 30 * time.Minute},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got := r.IntervalFor(tt.feed)
+				if got != tt.expected {
+					t.Errorf("IntervalFor() = %s, want %s", got, tt.expected)
+				}
+			})
+		}
+	})
+
+	t.Run("Should return feeds due for refresh", func(t *testing.T) {
+		now := time.Now()
+		r := RefreshConfig{
+			Interval: time.Hour,
+			Feeds:    map[string]time.Duration{"disabled.com": 0},
+		}
+
+		due := &RssFeed{Url: "due.com", Category: "Fun", LastRefresh: now.Add(-2 * time.Hour)}
+		never := &RssFeed{Url: "never.com", Category: "Fun"}
+		feeds := []*RssFeed{
+			due,
+			never,
+			{Url: "recent.com", Category: "Fun", LastRefresh: now.Add(-time.Minute)},
+			{Url: "disabled.com", Category: "Fun"},
+			{Url: "waiting.com", Category: "Fun", RetryAt: now.Add(time.Hour)},
+			{Url: "scheduled.com", Category: "Fun", NextRefresh: now.Add(time.Hour)},
+			{Url: "Bookmarks"},
+		}
+
+		got := r.DueFeeds(feeds, now)
+		if len(got) != 2 || got[0] != due || got[1] != never {
+			t.Errorf("Wrong feeds due, got %d feeds", len(got))
+		}
+	})
+}
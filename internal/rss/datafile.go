@@ -0,0 +1,222 @@
+package rss
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DataBackups is how many earlier versions of data.json are kept next to
+// it, from data.json.1, the newest, to data.json.3.
+const DataBackups = 3
+
+// dataLockWait is how long loading or saving waits for another instance
+// to be done with the data file.
+var dataLockWait = 5 * time.Second
+
+// withDataLock runs fn while holding the lock on the data file at path, so
+// instances sharing the cache dir load and save one at a time.
+func withDataLock(path string, fn func() error) error {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	deadline := time.Now().Add(dataLockWait)
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			return err
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			return ErrDataFileLocked
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	defer unlock(f)
+
+	return fn()
+}
+
+// saveDataFile writes the list to the data file at path. The list is
+// written to a temporary file that is synced and renamed over the old file,
+// so a crash or a full disk leaves either the old or the new file. The old
+// file becomes the newest backup.
+func saveDataFile(l *List, path string, now time.Time) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// Does nothing once the file was renamed
+	defer os.Remove(tmp.Name())
+
+	if err := l.Save(tmp, now); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := rotateBackups(path); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// loadDataFile restores the list from the data file at path. When the file
+// is missing or does not decode, the newest backup that does is used and
+// ErrDataFileRestored is returned with its name. No data file and no
+// backups is a fresh start.
+func loadDataFile(l *List, path string) error {
+	err := restoreFile(l, path)
+	if err == nil {
+		return nil
+	}
+
+	for i := 1; i <= DataBackups; i++ {
+		backup := backupPath(path, i)
+		if restoreFile(l, backup) == nil {
+			return fmt.Errorf("%w %s: %v", ErrDataFileRestored, filepath.Base(backup), err)
+		}
+	}
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// mergeDataFile merges what another instance saved to the data file at
+// path since l was loaded or saved: read and bookmark state that changed
+// later, by item Ts, items l does not have and items that were pruned.
+// Items l pruned are not added back unless they were bookmarked since. A
+// file that does not decode is left to the save to replace.
+func mergeDataFile(l *List, path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var saved List
+	if err := json.NewDecoder(f).Decode(&saved); err != nil {
+		return nil
+	}
+	if saved.Ts == l.Ts {
+		return nil
+	}
+
+	l.mergeFeeds(saved.Feeds)
+	return nil
+}
+
+func (l *List) mergeFeeds(saved []*RssFeed) {
+	l.ReindexList()
+
+	for _, savedFeed := range saved {
+		feed := l.FeedIndex[savedFeed.Url]
+		if feed == nil || savedFeed.Url == "Bookmarks" {
+			continue
+		}
+		if feed.Feed == nil {
+			feed.Feed = savedFeed.Feed
+		}
+		if len(savedFeed.Pruned) > 0 {
+			l.dropPruned(feed, savedFeed.Pruned)
+		}
+
+		added := false
+		for _, savedItem := range savedFeed.RssItems {
+			if savedItem.Item == nil || (feed.Pruned[savedItem.GUID()] && !savedItem.Bookmark) {
+				continue
+			}
+
+			item := l.ItemIndex[savedItem.GUID()]
+			if item == nil {
+				delete(feed.Pruned, savedItem.GUID())
+				feed.RssItems = append(feed.RssItems, savedItem)
+				l.ItemIndex[savedItem.GUID()] = savedItem
+				if savedItem.Bookmark {
+					l.SetBookmark(true, savedItem)
+				}
+				added = true
+				continue
+			}
+
+			if savedItem.Ts > item.Ts {
+				item.Ts = savedItem.Ts
+				item.Read = savedItem.Read
+				l.SetBookmark(savedItem.Bookmark, item)
+			}
+		}
+		// Items from the other instance are appended after the ones here
+		if added {
+			feed.SortByDate()
+		}
+	}
+}
+
+func restoreFile(l *List, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return l.Restore(f)
+}
+
+// rotateBackups moves every backup one place down, dropping the oldest, and
+// moves the data file to the newest place.
+func rotateBackups(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	for i := DataBackups - 1; i > 0; i-- {
+		err := os.Rename(backupPath(path, i), backupPath(path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return os.Rename(path, backupPath(path, 1))
+}
+
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// syncDir makes renames in dir durable. Not every platform can sync a
+// directory, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
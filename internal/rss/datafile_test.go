@@ -0,0 +1,277 @@
+package rss
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestDataFile(t *testing.T) {
+	newDataList := func(title string) *List {
+		l := NewListWithDefaults()
+		feed := l.AddFeed("golang", FeedSettings{Url: "https://example.com/feed"})
+		feed.RssItems = []*RssItem{{Item: &gofeed.Item{Title: title, GUID: "1"}, Read: true}}
+		return l
+	}
+
+	restoredTitle := func(t *testing.T, l *List) string {
+		t.Helper()
+		items := l.FeedIndex["https://example.com/feed"].RssItems
+		if len(items) != 1 {
+			t.Fatalf("Expected 1 item, got %d", len(items))
+		}
+		if !items[0].Read {
+			t.Error("Read state not restored")
+		}
+		return items[0].Item.Title
+	}
+
+	t.Run("Should save and load list", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json")
+
+		if err := saveDataFile(newDataList("first"), path, time.Now()); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		l := newDataList("")
+		l.FeedIndex["https://example.com/feed"].RssItems = nil
+		if err := loadDataFile(l, path); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if title := restoredTitle(t, l); title != "first" {
+			t.Errorf("got %q, want first", title)
+		}
+	})
+
+	t.Run("Should keep backups and no temporary files", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "data.json")
+
+		for i := range DataBackups + 3 {
+			if err := saveDataFile(newDataList(string(rune('a'+i))), path, time.Now()); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		want := "data.json data.json.1 data.json.2 data.json.3"
+		if got := strings.Join(names, " "); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		l := NewListWithDefaults()
+		l.AddFeed("golang", FeedSettings{Url: "https://example.com/feed"})
+		if err := restoreFile(l, backupPath(path, 1)); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if title := restoredTitle(t, l); title != "e" {
+			t.Errorf("Newest backup should be the previous save, got %q", title)
+		}
+	})
+
+	t.Run("Should fall back to the newest valid backup", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json")
+		for _, title := range []string{"old", "good"} {
+			if err := saveDataFile(newDataList(title), path, time.Now()); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+		}
+		// A save that was cut off halfway
+		if err := os.WriteFile(path, []byte(`{"Feeds":[{"Url":`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		l := NewListWithDefaults()
+		l.AddFeed("golang", FeedSettings{Url: "https://example.com/feed"})
+		err := loadDataFile(l, path)
+		if !errors.Is(err, ErrDataFileRestored) {
+			t.Fatalf("Expected ErrDataFileRestored, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "data.json.1") {
+			t.Errorf("Error should name the backup, got %q", err)
+		}
+		if title := restoredTitle(t, l); title != "old" {
+			t.Errorf("got %q, want old", title)
+		}
+	})
+
+	t.Run("Should skip backups that are corrupt too", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json")
+		for _, title := range []string{"good", "newer", "newest"} {
+			if err := saveDataFile(newDataList(title), path, time.Now()); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+		}
+		for _, p := range []string{path, backupPath(path, 1)} {
+			if err := os.WriteFile(p, []byte("{"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		l := NewListWithDefaults()
+		l.AddFeed("golang", FeedSettings{Url: "https://example.com/feed"})
+		err := loadDataFile(l, path)
+		if !errors.Is(err, ErrDataFileRestored) || !strings.Contains(err.Error(), "data.json.2") {
+			t.Fatalf("Expected restore from data.json.2, got %v", err)
+		}
+		if title := restoredTitle(t, l); title != "good" {
+			t.Errorf("got %q, want good", title)
+		}
+	})
+
+	t.Run("Should start fresh without a data file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json")
+
+		l := NewListWithDefaults()
+		if err := loadDataFile(l, path); err != nil {
+			t.Errorf("Unexpected error: %q", err)
+		}
+	})
+
+	t.Run("Should report a corrupt data file without backups", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json")
+		if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := loadDataFile(NewListWithDefaults(), path)
+		if err == nil || errors.Is(err, ErrDataFileRestored) {
+			t.Errorf("Expected decode error, got %v", err)
+		}
+	})
+
+	t.Run("Should merge what another instance saved", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json")
+		if err := saveDataFile(newDataList("first"), path, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		load := func() *List {
+			l := NewListWithDefaults()
+			l.AddFeed("golang", FeedSettings{Url: "https://example.com/feed"})
+			if err := loadDataFile(l, path); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+			return l
+		}
+		a, b := load(), load()
+
+		// a marks the item unread and fetches a new one, b bookmarks the new
+		// item before a saves, then b saves
+		aItem := a.ItemIndex["1"]
+		aItem.ToggleRead()
+		aFeed := a.FeedIndex["https://example.com/feed"]
+		aFeed.RssItems = append(aFeed.RssItems, &RssItem{Item: &gofeed.Item{Title: "second", GUID: "2"}})
+		if err := saveDataFile(a, path, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := mergeDataFile(b, path); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		items := b.FeedIndex["https://example.com/feed"].RssItems
+		if len(items) != 2 {
+			t.Fatalf("Expected the item fetched by a, got %d items", len(items))
+		}
+		if items[0].Read {
+			t.Error("Expected read state changed later by a")
+		}
+
+		b.ItemIndex["2"].ToggleBookmark()
+		b.SetBookmark(true, b.ItemIndex["2"])
+		if err := saveDataFile(b, path, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := mergeDataFile(a, path); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if !a.ItemIndex["2"].Bookmark || len(a.Bookmarks().RssItems) != 1 {
+			t.Error("Expected bookmark made by b")
+		}
+		if a.ItemIndex["1"].Read {
+			t.Error("Should keep its own read state")
+		}
+	})
+
+	t.Run("Should keep items merged from another instance newest first", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json")
+		dated := func(guid string, sec int64) *RssItem {
+			published := time.Unix(sec, 0)
+			return &RssItem{Item: &gofeed.Item{Title: guid, GUID: guid, Published: published.Format(time.RFC3339), PublishedParsed: &published}}
+		}
+
+		other := newDataList("")
+		other.FeedIndex["https://example.com/feed"].RssItems = []*RssItem{dated("new", 200), dated("old", 100)}
+		if err := saveDataFile(other, path, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		l := newDataList("")
+		feed := l.FeedIndex["https://example.com/feed"]
+		feed.RssItems = []*RssItem{dated("old", 100)}
+		if err := mergeDataFile(l, path); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if len(feed.RssItems) != 2 || feed.RssItems[0].GUID() != "new" {
+			t.Fatalf("Expected the merged item first of 2, got %q first of %d", feed.RssItems[0].GUID(), len(feed.RssItems))
+		}
+		if latest := feed.LatestItem(); latest == nil || latest.GUID() != "new" {
+			t.Error("Expected the merged item as latest")
+		}
+	})
+
+	t.Run("Should skip merging its own save", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json")
+		l := newDataList("first")
+		if err := saveDataFile(l, path, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		// Changed without a Ts, a merge would take the saved state
+		l.ItemIndex["1"] = l.FeedIndex["https://example.com/feed"].RssItems[0]
+		l.ItemIndex["1"].Read = false
+		if err := mergeDataFile(l, path); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if l.ItemIndex["1"].Read {
+			t.Error("Should not merge the file the list saved itself")
+		}
+	})
+
+	t.Run("Should wait for the lock and give up", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.json")
+		wait := dataLockWait
+		dataLockWait = 100 * time.Millisecond
+		defer func() { dataLockWait = wait }()
+
+		err := withDataLock(path, func() error {
+			return withDataLock(path, func() error {
+				t.Error("Should not run while the lock is held")
+				return nil
+			})
+		})
+		if !errors.Is(err, ErrDataFileLocked) {
+			t.Errorf("Expected ErrDataFileLocked, got %v", err)
+		}
+
+		ran := false
+		if err := withDataLock(path, func() error { ran = true; return nil }); err != nil || !ran {
+			t.Errorf("Lock should be free again, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxDiscoverBody is how much of a web page is read when looking for feeds.
+// Feeds are read in full.
+const maxDiscoverBody = 2 << 20
+
+// sniffLen is how much of a body is read to tell a web page from a feed
+const sniffLen = 512
+
+// feedPaths are tried when a page does not link to its feed
+var feedPaths = []string{"/feed", "/rss.xml", "/atom.xml"}
+
+// feedTypes are the link types that point to a feed
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// DiscoveredFeed is a feed found for a web page.
+type DiscoveredFeed struct {
+	Url   string
+	Title string
+}
+
+// Discover finds the feeds of a web page. A feed URL is returned as is.
+// Otherwise the feeds the page links to with rel="alternate" are returned,
+// or else every common feed path that has a feed.
+func Discover(ctx context.Context, pageUrl string) ([]DiscoveredFeed, error) {
+	body, contentType, err := get(ctx, pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if feed, err := newParser().Parse(bytes.NewReader(body)); err == nil {
+		return []DiscoveredFeed{{Url: pageUrl, Title: clean(feed.Title)}}, nil
+	}
+
+	if !isHTML(contentType, body) {
+		return nil, ErrNoFeedFound
+	}
+
+	base, err := url.Parse(pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if found := feedLinks(base, body); len(found) > 0 {
+		return found, nil
+	}
+
+	var found []DiscoveredFeed
+	for _, p := range feedPaths {
+		candidate := base.ResolveReference(&url.URL{Path: p}).String()
+		body, _, err := get(ctx, candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if feed, err := newParser().Parse(bytes.NewReader(body)); err == nil {
+			found = append(found, DiscoveredFeed{Url: candidate, Title: clean(feed.Title)})
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, ErrNoFeedFound
+	}
+	return found, nil
+}
+
+func get(ctx context.Context, u string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	sniff := make([]byte, sniffLen)
+	n, err := io.ReadFull(resp.Body, sniff)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, "", err
+	}
+	sniff = sniff[:n]
+
+	// Only web pages are cut short, a feed would not parse
+	contentType := resp.Header.Get("Content-Type")
+	rest := io.Reader(resp.Body)
+	if isHTML(contentType, sniff) && !strings.HasPrefix(http.DetectContentType(sniff), "text/xml") {
+		rest = io.LimitReader(resp.Body, maxDiscoverBody-int64(n))
+	}
+
+	body, err := io.ReadAll(io.MultiReader(bytes.NewReader(sniff), rest))
+	return body, contentType, err
+}
+
+// isHTML reports whether a response is a web page rather than a feed
+func isHTML(contentType string, body []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+			return true
+		}
+	}
+	return strings.HasPrefix(http.DetectContentType(body), "text/html")
+}
+
+// feedLinks returns the feeds linked from the head of an HTML page,
+// resolved against base or the <base> of the page.
+func feedLinks(base *url.URL, body []byte) []DiscoveredFeed {
+	var found []DiscoveredFeed
+	seen := make(map[string]bool)
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return found
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				return found
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if !hasAttr {
+				continue
+			}
+
+			attrs := make(map[string]string)
+			for {
+				key, val, more := z.TagAttr()
+				attrs[strings.ToLower(string(key))] = string(val)
+				if !more {
+					break
+				}
+			}
+
+			switch string(name) {
+			case "base":
+				if href, err := url.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+					base = base.ResolveReference(href)
+				}
+			case "link":
+				if !hasToken(attrs["rel"], "alternate") || !feedTypes[strings.ToLower(attrs["type"])] {
+					continue
+				}
+				href, err := url.Parse(strings.TrimSpace(attrs["href"]))
+				if err != nil || attrs["href"] == "" {
+					continue
+				}
+				u := base.ResolveReference(href).String()
+				if seen[u] {
+					continue
+				}
+				seen[u] = true
+				found = append(found, DiscoveredFeed{Url: u, Title: clean(attrs["title"])})
+			}
+		}
+	}
+}
+
+// hasToken reports whether the space separated list contains token
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func ServerSite(t *testing.T, pages map[string]string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if page == "feed" {
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write(testData(t, "feed.xml"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+}
+
+func TestDiscover(t *testing.T) {
+	t.Run("Should find feeds linked from page", func(t *testing.T) {
+		server := ServerSite(t, map[string]string{
+			"/blog/": `<html><head>
+<link rel="stylesheet" href="/style.css">
+<link rel="alternate" type="application/rss+xml" title="RSS" href="feed.xml">
+<link rel="alternate" type="application/atom+xml" title="Atom" href="/atom">
+<link rel="alternate" type="application/rss+xml" href="feed.xml">
+</head><body><link rel="alternate" type="application/rss+xml" href="/late.xml"></body></html>`,
+		})
+		defer server.Close()
+
+		found, err := Discover(context.Background(), server.URL+"/blog/")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if len(found) != 2 {
+			t.Fatalf("got %d feeds, want 2: %+v", len(found), found)
+		}
+		if found[0].Url != server.URL+"/blog/feed.xml" || found[0].Title != "RSS" {
+			t.Errorf("Unexpected feed: %+v", found[0])
+		}
+		if found[1].Url != server.URL+"/atom" {
+			t.Errorf("Unexpected feed: %+v", found[1])
+		}
+	})
+
+	t.Run("Should resolve links against base", func(t *testing.T) {
+		server := ServerSite(t, map[string]string{
+			"/": `<head><base href="/site/"><link rel="Alternate home" type="application/feed+json" href="feed.json"></head>`,
+		})
+		defer server.Close()
+
+		found, err := Discover(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if len(found) != 1 || found[0].Url != server.URL+"/site/feed.json" {
+			t.Errorf("Unexpected feeds: %+v", found)
+		}
+	})
+
+	t.Run("Should try common feed paths", func(t *testing.T) {
+		server := ServerSite(t, map[string]string{
+			"/":         `<html><head><title>No links</title></head></html>`,
+			"/rss.xml":  "feed",
+			"/atom.xml": "feed",
+		})
+		defer server.Close()
+
+		found, err := Discover(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if len(found) != 2 || found[0].Url != server.URL+"/rss.xml" || found[0].Title != "NASA Space Station News" {
+			t.Fatalf("Unexpected feeds: %+v", found)
+		}
+		if found[1].Url != server.URL+"/atom.xml" {
+			t.Errorf("Unexpected feed: %+v", found[1])
+		}
+	})
+
+	t.Run("Should return feed URL as is", func(t *testing.T) {
+		server := ServerSite(t, map[string]string{"/feed": "feed"})
+		defer server.Close()
+
+		found, err := Discover(context.Background(), server.URL+"/feed")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if len(found) != 1 || found[0].Url != server.URL+"/feed" {
+			t.Errorf("Unexpected feeds: %+v", found)
+		}
+	})
+
+	t.Run("Should read feeds larger than a page in full", func(t *testing.T) {
+		var feed strings.Builder
+		feed.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel><title>Large</title>`)
+		for i := 0; feed.Len() <= maxDiscoverBody; i++ {
+			fmt.Fprintf(&feed, "<item><guid>%d</guid><description>%s</description></item>", i, strings.Repeat("x", 1000))
+		}
+		feed.WriteString("</channel></rss>")
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Served as a page, the body tells it is a feed
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(feed.String()))
+		}))
+		defer server.Close()
+
+		found, err := Discover(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if len(found) != 1 || found[0].Url != server.URL || found[0].Title != "Large" {
+			t.Errorf("Unexpected feeds: %+v", found)
+		}
+	})
+
+	t.Run("Should report page without feeds", func(t *testing.T) {
+		server := ServerSite(t, map[string]string{"/": `<html><body>Hello</body></html>`})
+		defer server.Close()
+
+		_, err := Discover(context.Background(), server.URL)
+		assertError(t, err, ErrNoFeedFound)
+	})
+
+	t.Run("Should report web page when fetching feed", func(t *testing.T) {
+		server := ServerSite(t, map[string]string{"/": `<html><body>Hello</body></html>`})
+		defer server.Close()
+
+		feed := &RssFeed{Url: server.URL}
+		err := feed.GetFeed()
+		assertError(t, err, ErrHTMLPage)
+	})
+}
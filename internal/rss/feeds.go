@@ -1,10 +1,11 @@
 package rss
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"sort"
-	"sync"
 	"time"
 
 	"github.com/mmcdole/gofeed"
@@ -13,30 +14,71 @@ import (
 type RssFeed struct {
 	Url      string
 	Category string
-	Error    string
+	// Categories lists every category and tag the feed appears under in
+	// urls.yaml, starting with Category. The feed is shared between them.
+	Categories []string `json:"-"`
+	Error      string
+	// Settings from urls.yaml, loaded fresh on every start
+	Settings FeedSettings `json:"-"`
+
+	// Validators from the last successful response, sent back as
+	// If-None-Match / If-Modified-Since on the next refresh.
+	ETag         string
+	LastModified string
+
+	// Consecutive failed refreshes and the earliest time the feed may be
+	// fetched again, see backoff.go.
+	Failures int
+	RetryAt  time.Time
+
+	// Earliest time the feed asked to be polled again, see schedule.go.
+	NextRefresh time.Time
+	// Last time a refresh of the feed finished, successful or not.
+	LastRefresh time.Time
 
 	Feed     *gofeed.Feed
 	RssItems []*RssItem
-	ts       time.Time
+	// Keys of items removed by the retention settings that the feed still
+	// lists, so they are not added back, see retention.go.
+	Pruned map[string]bool `json:",omitempty"`
 }
 
 type FeedResult struct {
-	Feed *RssFeed
-	Err  error
+	Feed        *RssFeed
+	Err         error
+	NotModified bool
+	// Cancelled is set when the refresh was stopped before this feed
+	// finished, either by the caller or by the overall deadline.
+	Cancelled bool
+	// Skipped is set when the feed was not due yet or already refreshing,
+	// and was left alone.
+	Skipped bool
 }
 
-func (f *RssFeed) existingKeys() map[string]struct{} {
+var httpClient = &http.Client{}
+
+// existingKeys returns the keys of the stored items. Links stored before
+// rewriting was turned on are added rewritten as well.
+func (f *RssFeed) existingKeys(rewrite *Rewriter) map[string]struct{} {
 	existing := make(map[string]struct{}, len(f.RssItems))
 	for _, item := range f.RssItems {
 		if item.Item.GUID != "" {
 			existing[item.Item.GUID] = struct{}{}
 		} else if item.Item.Link != "" {
 			existing[item.Item.Link] = struct{}{}
+			existing[rewrite.Apply(item.Item.Link)] = struct{}{}
 		}
 	}
 	return existing
 }
 
+func (f *RssFeed) categories() []string {
+	if len(f.Categories) == 0 && f.Category != "" {
+		return []string{f.Category}
+	}
+	return f.Categories
+}
+
 func (f *RssFeed) Link() (string, error) {
 	raw := f.Url
 	if f.Feed != nil {
@@ -89,6 +131,17 @@ func (f *RssFeed) HasUnread() bool {
 	return false
 }
 
+// BookmarkCount returns how many items of f are bookmarked.
+func (f *RssFeed) BookmarkCount() int {
+	n := 0
+	for _, item := range f.RssItems {
+		if item.Bookmark {
+			n++
+		}
+	}
+	return n
+}
+
 func (f *RssFeed) MarkAllItemsRead() {
 	for i := range f.RssItems {
 		f.RssItems[i].Ts = time.Now().UnixNano()
@@ -97,13 +150,7 @@ func (f *RssFeed) MarkAllItemsRead() {
 }
 
 func (f *RssFeed) Title() string {
-	var title string
-	if f.Feed == nil || f.Feed.Title == "" {
-		title = f.Url
-	} else {
-		title = f.Feed.Title
-	}
-
+	title := f.Name()
 	if f.HasUnread() {
 		return fmt.Sprintf("+ %s", title)
 	}
@@ -111,12 +158,27 @@ func (f *RssFeed) Title() string {
 	return title
 }
 
+// Name is the title of the feed without the unread marker
+func (f *RssFeed) Name() string {
+	switch {
+	case f.Settings.Title != "":
+		return f.Settings.Title
+	case f.Feed == nil || f.Feed.Title == "":
+		return f.Url
+	default:
+		return f.Feed.Title
+	}
+}
+
 func (f *RssFeed) Description() string {
 	return f.Feed.Description
 }
 
 func (f *RssFeed) Latest() string {
 	if f.Error != "" {
+		if retry := f.nextRetry(time.Now()); retry != "" {
+			return fmt.Sprintf("%s (retry %s)", f.Error, retry)
+		}
 		return f.Error
 	}
 
@@ -152,23 +214,102 @@ func (f *RssFeed) latestUnread() *RssItem {
 }
 
 func (f *RssFeed) GetFeed() error {
-	if f.Url == "" {
-		return ErrFeedHasNoUrl
+	return f.GetFeedContext(context.Background())
+}
+
+func (f *RssFeed) GetFeedContext(ctx context.Context) error {
+	if !f.startRefresh() {
+		return ErrRefreshing
+	}
+	defer f.endRefresh()
+
+	_, err := f.refresh(ctx, time.Now(), nil)
+	return err
+}
+
+// refresh fetches the feed and records the outcome on it. Failures schedule
+// the next retry, a success clears the error and the backoff.
+func (f *RssFeed) refresh(ctx context.Context, now time.Time, rewrite *Rewriter) (bool, error) {
+	modified, err := f.fetch(ctx, rewrite)
+	if err == ErrFeedHasNoUrl {
+		return false, err
 	}
 
-	parsedFeed, err := gofeed.NewParser().ParseURL(f.Url)
+	f.LastRefresh = now
+
 	if err != nil {
 		f.Error = err.Error()
-		return err
+		f.scheduleRetry(err, now)
+		return false, err
+	}
+
+	f.Error = ""
+	f.Failures = 0
+	f.RetryAt = time.Time{}
+	f.NextRefresh = nextRefresh(f.Feed, now)
+	return modified, nil
+}
+
+// fetch downloads and merges the feed. Stored validators are only sent when
+// the feed is already loaded, so a 304 always has cached content behind it.
+// The returned bool is false when the server answered 304 Not Modified.
+func (f *RssFeed) fetch(ctx context.Context, rewrite *Rewriter) (bool, error) {
+	if f.Url == "" {
+		return false, ErrFeedHasNoUrl
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.Url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("User-Agent", UserAgent)
+	for k, v := range f.Settings.Headers {
+		req.Header.Set(k, v)
+	}
+	if f.Feed != nil {
+		if f.ETag != "" {
+			req.Header.Set("If-None-Match", f.ETag)
+		}
+		if f.LastModified != "" {
+			req.Header.Set("If-Modified-Since", f.LastModified)
+		}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && f.Feed != nil {
+		return false, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	parsedFeed, err := newParser().Parse(resp.Body)
+	if err != nil {
+		if isHTML(resp.Header.Get("Content-Type"), nil) {
+			return false, ErrHTMLPage
+		}
+		return false, err
 	}
 
 	sanitizeFeed(parsedFeed)
 
 	f.Feed = parsedFeed
-	f.mergeItems(parsedFeed.Items)
+	f.ETag = resp.Header.Get("ETag")
+	f.LastModified = resp.Header.Get("Last-Modified")
+	f.mergeItems(parsedFeed.Items, rewrite)
 	f.SortByDate()
-	f.Error = ""
-	return nil
+	return true, nil
 }
 
 func sanitizeFeed(f *gofeed.Feed) {
@@ -252,16 +393,24 @@ func (f *RssFeed) PrevBefore(current *RssItem) (int, *RssItem) {
 	return -1, nil
 }
 
-func (f *RssFeed) mergeItems(items []*gofeed.Item) {
-	existing := f.existingKeys()
+func (f *RssFeed) mergeItems(items []*gofeed.Item, rewrite *Rewriter) {
+	existing := f.existingKeys(rewrite)
+	fetched := make(map[string]struct{}, len(items))
 
 	for _, item := range items {
+		rewrite.Item(item)
+
 		key := item.GUID
 		if key == "" {
 			key = item.Link
 		}
+		fetched[key] = struct{}{}
+
+		if _, ok := existing[key]; ok || f.Pruned[key] {
+			continue
+		}
 
-		if _, ok := existing[key]; ok {
+		if f.Settings.filtered(item.Title) {
 			continue
 		}
 
@@ -274,35 +423,8 @@ func (f *RssFeed) mergeItems(items []*gofeed.Item) {
 		})
 		existing[key] = struct{}{}
 	}
-}
-
-func UpdateFeeds(feeds ...*RssFeed) (<-chan FeedResult, error) {
-	if len(feeds) == 0 {
-		return nil, ErrNoFeedsInList
-	}
-
-	results := make(chan FeedResult, len(feeds))
-	var wg sync.WaitGroup
-	wg.Add(len(feeds))
-
-	for _, feed := range feeds {
-		go func(f *RssFeed) {
-			defer wg.Done()
-			err := ErrCooldown
-			if time.Since(f.ts) >= 5*time.Second {
-				f.ts = time.Now()
-				err = f.GetFeed()
-			}
-			results <- FeedResult{Feed: f, Err: err}
-		}(feed)
-	}
-
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
 
-	return results, nil
+	f.forgetGone(fetched)
 }
 
 func MarkFeedsAsRead(feeds ...*RssFeed) {
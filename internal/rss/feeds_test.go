@@ -2,6 +2,7 @@ package rss
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -459,6 +460,99 @@ func TestFeeds(t *testing.T) {
 		}
 	})
 
+	t.Run("Should store validators from response", func(t *testing.T) {
+		lastModified := "Wed, 21 Oct 2015 07:28:00 GMT"
+		server := ServerConditional(t, testData(t, "feed.xml"), `"v1"`, lastModified)
+		defer server.Close()
+
+		rssFeed := RssFeed{Url: server.URL}
+
+		err := rssFeed.GetFeed()
+		if err != nil {
+			t.Fatalf("Error getting feed %q", err)
+		}
+
+		if rssFeed.ETag != `"v1"` {
+			t.Errorf("ETag not stored, got %q", rssFeed.ETag)
+		}
+
+		if rssFeed.LastModified != lastModified {
+			t.Errorf("Last-Modified not stored, got %q", rssFeed.LastModified)
+		}
+	})
+
+	t.Run("Should treat not modified as success", func(t *testing.T) {
+		server := ServerConditional(t, testData(t, "feed.xml"), `"v1"`, "")
+		defer server.Close()
+
+		rssFeed := RssFeed{Url: server.URL}
+
+		err := rssFeed.GetFeed()
+		if err != nil {
+			t.Fatalf("Error getting feed %q", err)
+		}
+
+		itemCount := len(rssFeed.RssItems)
+		rssFeed.Error = "Old error"
+
+		modified, err := rssFeed.refresh(context.Background(), time.Now(), nil)
+		if err != nil {
+			t.Fatalf("Not modified should not return error: %q", err)
+		}
+
+		if modified {
+			t.Error("Feed should be reported as not modified")
+		}
+
+		if rssFeed.Error != "" {
+			t.Error("Should unset error on feed")
+		}
+
+		if len(rssFeed.RssItems) != itemCount {
+			t.Error("Items should be kept when feed not modified")
+		}
+	})
+
+	t.Run("Should not send validators when feed not loaded", func(t *testing.T) {
+		server := ServerConditional(t, testData(t, "feed.xml"), `"v1"`, "")
+		defer server.Close()
+
+		rssFeed := RssFeed{Url: server.URL, ETag: `"v1"`}
+
+		modified, err := rssFeed.fetch(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Error getting feed %q", err)
+		}
+
+		if !modified || rssFeed.Feed == nil {
+			t.Error("Unloaded feed should be downloaded in full")
+		}
+	})
+
+	t.Run("Update feeds reports not modified", func(t *testing.T) {
+		server := ServerConditional(t, testData(t, "feed.xml"), `"v1"`, "")
+		defer server.Close()
+
+		rssFeed := &RssFeed{Url: server.URL}
+		if err := rssFeed.GetFeed(); err != nil {
+			t.Fatalf("Error getting feed %q", err)
+		}
+
+		results, err := UpdateFeeds(rssFeed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		res := <-results
+		if res.Err != nil {
+			t.Errorf("Unexpected error: %q", res.Err)
+		}
+
+		if !res.NotModified {
+			t.Error("Result should report not modified")
+		}
+	})
+
 	t.Run("Update feeds", func(t *testing.T) {
 		server := Server(t, testData(t, "feed.xml"))
 		defer server.Close()
@@ -503,29 +597,26 @@ func TestFeeds(t *testing.T) {
 		}
 	})
 
-	t.Run("Should have cooldown", func(t *testing.T) {
+	t.Run("Should skip feeds waiting to retry", func(t *testing.T) {
 		server := Server(t, testData(t, "feed.xml"))
 		defer server.Close()
 
 		feeds := []*RssFeed{
-			{Url: server.URL},
-		}
-
-		_, err := UpdateFeeds(feeds...)
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+			{Url: server.URL, RetryAt: time.Now().Add(time.Minute)},
 		}
 
-		time.Sleep(1 * time.Millisecond)
-
 		results, err := UpdateFeeds(feeds...)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
 		res := <-results
-		if res.Err != ErrCooldown {
-			t.Error("Expected cooldown error")
+		if res.Err != ErrBackoff {
+			t.Error("Expected backoff error")
+		}
+
+		if feeds[0].Feed != nil {
+			t.Error("Feed should not be fetched while backing off")
 		}
 	})
 
@@ -39,6 +39,15 @@ func UrlsFilePath() (string, error) {
 	return appDir, err
 }
 
+// OpenUrlsFile reads urls.yaml from the config dir for editing.
+func OpenUrlsFile() (*UrlsFile, error) {
+	dir, err := UrlsFilePath()
+	if err != nil {
+		return nil, err
+	}
+	return ReadUrlsFile(filepath.Join(dir, "urls.yaml"))
+}
+
 func ConfigFilePath() (string, error) {
 	dir, err := os.UserConfigDir()
 	if err != nil {
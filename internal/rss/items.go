@@ -72,7 +72,21 @@ func (i *RssItem) FilterContent() string {
 	return fmt.Sprintf("%s %s", i.Title(), i.Description())
 }
 
+// ContentOptions sets how RenderContent shows an item. Style is a glamour
+// style name or file, picked by the terminal background when empty.
+type ContentOptions struct {
+	Markdown bool
+	Width    int
+	Style    string
+}
+
+var DefaultContentOptions = ContentOptions{Markdown: true, Width: 80}
+
 func (i *RssItem) Content() string {
+	return i.RenderContent(DefaultContentOptions)
+}
+
+func (i *RssItem) RenderContent(opts ContentOptions) string {
 	time := i.Timestamp()
 	link := i.Link()
 	content := i.Item.Content
@@ -82,14 +96,21 @@ func (i *RssItem) Content() string {
 		content = i.Description()
 	}
 
-	r, err := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
-		glamour.WithWordWrap(80),
-	)
-	if err == nil {
-		render, err := r.Render(content)
+	if opts.Markdown {
+		style := glamour.WithAutoStyle()
+		if opts.Style != "" {
+			style = glamour.WithStylePath(opts.Style)
+		}
+
+		r, err := glamour.NewTermRenderer(
+			style,
+			glamour.WithWordWrap(opts.Width),
+		)
 		if err == nil {
-			content = render
+			render, err := r.Render(content)
+			if err == nil {
+				content = render
+			}
 		}
 	}
 
@@ -1,6 +1,7 @@
 package rss
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -130,4 +131,20 @@ func TestItems(t *testing.T) {
 			t.Error("Timestamp not updated after toggle bookmark")
 		}
 	})
+
+	t.Run("Should render content as plain text", func(t *testing.T) {
+		rssItem := RssItem{
+			Item: &gofeed.Item{Content: "Some **bold** text"},
+		}
+
+		plain := rssItem.RenderContent(ContentOptions{Markdown: false, Width: 80})
+		if !strings.Contains(plain, "Some **bold** text") {
+			t.Errorf("Content should not be rendered, got %q", plain)
+		}
+
+		rendered := rssItem.RenderContent(ContentOptions{Markdown: true, Width: 80, Style: "dark"})
+		if strings.Contains(rendered, "**bold**") {
+			t.Errorf("Content should be rendered, got %q", rendered)
+		}
+	})
 }
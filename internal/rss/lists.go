@@ -1,10 +1,11 @@
 package rss
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/fs"
-	"os"
 	"slices"
 	"sort"
 	"time"
@@ -18,14 +19,36 @@ type List struct {
 	CategoryIndex map[string][]*RssFeed `json:"-"`
 	ItemIndex     map[string]*RssItem   `json:"-"`
 	Ts            int64
+
+	// CategoryOrder is the order categories appear in urls.yaml
+	CategoryOrder []string `json:"-"`
+	// SortCategories lists categories alphabetically instead of in file
+	// order, see Config.SortCategories.
+	SortCategories bool `json:"-"`
+	// Storage the list was loaded from and is saved to, data.json when nil
+	Storage Storage `json:"-"`
 }
 
+// Categories returns the categories in the order of urls.yaml. Tags and
+// other categories that are not keys in the file follow, sorted.
 func (l *List) Categories() []string {
-	var categories []string
+	var categories, rest []string
+	for _, category := range l.CategoryOrder {
+		if _, ok := l.CategoryIndex[category]; ok && !slices.Contains(categories, category) {
+			categories = append(categories, category)
+		}
+	}
 	for category := range l.CategoryIndex {
-		categories = append(categories, category)
+		if !slices.Contains(categories, category) {
+			rest = append(rest, category)
+		}
+	}
+	sort.Strings(rest)
+	categories = append(categories, rest...)
+
+	if l.SortCategories {
+		sort.Strings(categories)
 	}
-	sort.Strings(categories)
 	return categories
 }
 
@@ -39,6 +62,28 @@ func (l *List) GetCategory(category string) ([]*RssFeed, error) {
 	return l.CategoryIndex[category], nil
 }
 
+// Subscriptions returns the feeds listed in urls.yaml, without bookmarks.
+func (l *List) Subscriptions() []*RssFeed {
+	var feeds []*RssFeed
+	for _, feed := range l.Feeds {
+		if feed.Category != "" {
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds
+}
+
+// FeedOf returns the feed listed in urls.yaml that the item belongs to, so
+// bookmarked items can be traced back to their feed.
+func (l *List) FeedOf(item *RssItem) *RssFeed {
+	for _, feed := range l.Subscriptions() {
+		if slices.Contains(feed.RssItems, item) {
+			return feed
+		}
+	}
+	return nil
+}
+
 func (l *List) Add(feeds ...*RssFeed) {
 	l.Feeds = append(l.Feeds, feeds...)
 }
@@ -77,6 +122,10 @@ func (l *List) UpdateAllFeeds() (<-chan FeedResult, error) {
 	return UpdateFeeds(l.Feeds...)
 }
 
+func (l *List) UpdateAllFeedsContext(ctx context.Context, opts UpdateOptions) (<-chan FeedResult, error) {
+	return UpdateFeedsContext(ctx, opts, l.Feeds...)
+}
+
 func (l *List) CreateFeedsFromYaml(filesystem fs.FS, filename string) error {
 	file, err := filesystem.Open(filename)
 	if err != nil {
@@ -86,21 +135,48 @@ func (l *List) CreateFeedsFromYaml(filesystem fs.FS, filename string) error {
 
 	data, _ := io.ReadAll(file)
 
-	var raw map[string][]string
+	var raw map[string][]FeedSettings
 	if err := yaml.Unmarshal(data, &raw); err != nil {
 		return err
 	}
 
+	// A map loses the order of the file, read the keys again in order
+	var order yaml.MapSlice
+	if err := yaml.Unmarshal(data, &order); err != nil {
+		return err
+	}
+
+	// A URL listed more than once is one feed shown in every category it is
+	// listed under. Its settings come from the first entry.
+	parsed := make(map[string]*RssFeed)
+
 	var feeds []*RssFeed
-	for category, urls := range raw {
-		for _, u := range urls {
-			feed := &RssFeed{
-				Url:      u,
-				Category: category,
+	for _, item := range order {
+		category := fmt.Sprint(item.Key)
+		entries, ok := raw[category]
+		if !ok {
+			continue
+		}
+		if !slices.Contains(l.CategoryOrder, category) {
+			l.CategoryOrder = append(l.CategoryOrder, category)
+		}
+		for _, settings := range entries {
+			feed, ok := parsed[settings.Url]
+			if !ok {
+				feed = &RssFeed{
+					Url:      settings.Url,
+					Category: category,
+					Settings: settings,
+				}
+				parsed[settings.Url] = feed
+				l.FeedIndex[settings.Url] = feed
+				feeds = append(feeds, feed)
+			}
+
+			l.addToCategory(feed, category)
+			for _, tag := range settings.Tags {
+				l.addToCategory(feed, tag)
 			}
-			l.FeedIndex[u] = feed
-			l.CategoryIndex[category] = append(l.CategoryIndex[category], feed)
-			feeds = append(feeds, feed)
 		}
 	}
 
@@ -109,6 +185,109 @@ func (l *List) CreateFeedsFromYaml(filesystem fs.FS, filename string) error {
 	return nil
 }
 
+func (l *List) addToCategory(feed *RssFeed, category string) {
+	if slices.Contains(feed.Categories, category) {
+		return
+	}
+	feed.Categories = append(feed.Categories, category)
+	l.CategoryIndex[category] = append(l.CategoryIndex[category], feed)
+}
+
+// AddFeed adds a feed to the category, or the category to the feed when the
+// URL is already in the list.
+func (l *List) AddFeed(category string, s FeedSettings) *RssFeed {
+	feed := l.FeedIndex[s.Url]
+	if feed == nil {
+		feed = &RssFeed{Url: s.Url, Category: category, Settings: s}
+		l.FeedIndex[s.Url] = feed
+		l.Feeds = append(l.Feeds, feed)
+	}
+
+	if !slices.Contains(l.CategoryOrder, category) {
+		l.CategoryOrder = append(l.CategoryOrder, category)
+	}
+	l.addToCategory(feed, category)
+	for _, tag := range s.Tags {
+		l.addToCategory(feed, tag)
+	}
+	return feed
+}
+
+// RemoveFeed removes the feed from every category, and its items from the
+// indexes and bookmarks.
+func (l *List) RemoveFeed(url string) {
+	feed := l.FeedIndex[url]
+	if feed == nil {
+		return
+	}
+
+	l.removeFromCategories(feed)
+	delete(l.FeedIndex, url)
+	l.Feeds = slices.DeleteFunc(l.Feeds, func(f *RssFeed) bool { return f == feed })
+
+	for _, item := range feed.RssItems {
+		if item.Item != nil && l.ItemIndex[item.GUID()] == item {
+			delete(l.ItemIndex, item.GUID())
+		}
+		if item.Bookmark {
+			l.SetBookmark(false, item)
+		}
+	}
+}
+
+// MoveFeed moves the feed out of every category into category. Tags stay.
+func (l *List) MoveFeed(url, category string) error {
+	feed := l.FeedIndex[url]
+	if feed == nil {
+		return ErrFeedNotFound
+	}
+
+	l.removeFromCategories(feed)
+	feed.Category = category
+	if !slices.Contains(l.CategoryOrder, category) {
+		l.CategoryOrder = append(l.CategoryOrder, category)
+	}
+	l.addToCategory(feed, category)
+	for _, tag := range feed.Settings.Tags {
+		l.addToCategory(feed, tag)
+	}
+	return nil
+}
+
+// ReplaceFeed changes the URL of a feed. Items and settings are kept, the
+// error and validators of the old URL are cleared.
+func (l *List) ReplaceFeed(old, new string) error {
+	feed := l.FeedIndex[old]
+	if feed == nil {
+		return ErrFeedNotFound
+	}
+
+	delete(l.FeedIndex, old)
+	l.FeedIndex[new] = feed
+
+	feed.Url = new
+	feed.Settings.Url = new
+	feed.Error = ""
+	feed.ETag = ""
+	feed.LastModified = ""
+	feed.Failures = 0
+	feed.RetryAt = time.Time{}
+	feed.NextRefresh = time.Time{}
+	return nil
+}
+
+func (l *List) removeFromCategories(feed *RssFeed) {
+	for _, category := range feed.categories() {
+		feeds := slices.DeleteFunc(l.CategoryIndex[category], func(f *RssFeed) bool { return f == feed })
+		if len(feeds) == 0 {
+			delete(l.CategoryIndex, category)
+		} else {
+			l.CategoryIndex[category] = feeds
+		}
+	}
+	feed.Categories = nil
+}
+
 func (l *List) MarkAllFeedsRead() {
 	for _, feed := range l.Feeds {
 		feed.MarkAllItemsRead()
@@ -155,6 +334,7 @@ func (l *List) Restore(r io.Reader) error {
 	if err != nil {
 		return err
 	}
+	l.Ts = decoded.Ts
 
 	for _, decodedFeed := range decoded.Feeds {
 		if decodedFeed.Url == "Bookmarks" {
@@ -164,8 +344,15 @@ func (l *List) Restore(r io.Reader) error {
 		feed := l.FeedIndex[decodedFeed.Url]
 		if feed != nil {
 			feed.Error = decodedFeed.Error
+			feed.ETag = decodedFeed.ETag
+			feed.LastModified = decodedFeed.LastModified
+			feed.Failures = decodedFeed.Failures
+			feed.RetryAt = decodedFeed.RetryAt
+			feed.NextRefresh = decodedFeed.NextRefresh
+			feed.LastRefresh = decodedFeed.LastRefresh
 			feed.Feed = decodedFeed.Feed
 			feed.RssItems = decodedFeed.RssItems
+			feed.Pruned = decodedFeed.Pruned
 
 			for _, item := range feed.RssItems {
 				if item.Item != nil {
@@ -203,7 +390,16 @@ func NewListWithDefaults() *List {
 	}
 }
 
+// LoadList creates the feeds from urls.yaml and restores them from the data
+// file in the cache dir. ErrDataFileRestored means the data file could not
+// be read and a backup was used, the list is usable.
 func LoadList(filesystem fs.FS) (*List, error) {
+	return LoadListFrom(filesystem, nil)
+}
+
+// LoadListFrom creates the feeds from urls.yaml and restores them from s,
+// or from data.json when s is nil.
+func LoadListFrom(filesystem fs.FS, s Storage) (*List, error) {
 	l := NewListWithDefaults()
 
 	err := l.CreateFeedsFromYaml(filesystem, "urls.yaml")
@@ -211,21 +407,26 @@ func LoadList(filesystem fs.FS) (*List, error) {
 		return l, err
 	}
 
-	dataFilePath, err := DataFilePath()
-	if err != nil {
-		return l, err
+	if s == nil {
+		if s, err = OpenStorage(StorageJSON); err != nil {
+			return l, err
+		}
 	}
+	l.Storage = s
 
-	f, err := os.Open(dataFilePath)
-	if err != nil {
-		return l, err
-	}
-	defer f.Close()
+	return l, s.Load(l)
+}
 
-	err = l.Restore(f)
-	if err != nil {
-		return l, err
+// SaveList writes the list to its storage, after merging what other
+// instances saved to it since.
+func SaveList(l *List) error {
+	if l.Storage == nil {
+		s, err := OpenStorage(StorageJSON)
+		if err != nil {
+			return err
+		}
+		l.Storage = s
 	}
 
-	return l, nil
+	return l.Storage.Save(l)
 }
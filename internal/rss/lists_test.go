@@ -2,6 +2,8 @@ package rss
 
 import (
 	"bytes"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"testing"
 	"testing/fstest"
@@ -53,6 +55,12 @@ func newList() List {
 }
 
 func TestLists(t *testing.T) {
+	// LoadList reads and locks data.json in the cache dir, keep it away
+	// from the real one
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+
 	t.Run("Should marshal feed list to JSON", func(t *testing.T) {
 		l := newList()
 
@@ -138,6 +146,32 @@ func TestLists(t *testing.T) {
 		}
 	})
 
+	t.Run("Should restore feed validators", func(t *testing.T) {
+		saved := NewListWithDefaults()
+		saved.Feeds = append(saved.Feeds, &RssFeed{
+			Url:          "example.com",
+			ETag:         `"v1"`,
+			LastModified: "Wed, 21 Oct 2015 07:28:00 GMT",
+		})
+
+		var buf bytes.Buffer
+		if err := saved.Save(&buf, time.Now()); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		l := NewListWithDefaults()
+		feed := &RssFeed{Url: "example.com"}
+		l.FeedIndex[feed.Url] = feed
+
+		if err := l.Restore(&buf); err != nil {
+			t.Fatalf("Unexpected error restoring: %q", err)
+		}
+
+		if feed.ETag != `"v1"` || feed.LastModified == "" {
+			t.Error("Validators not restored")
+		}
+	})
+
 	t.Run("Should store timestamp in list", func(t *testing.T) {
 		l := newList()
 
@@ -351,6 +385,122 @@ func TestLists(t *testing.T) {
 		}
 	})
 
+	t.Run("Should keep category order from YAML", func(t *testing.T) {
+		l := NewListWithDefaults()
+		fs := fstest.MapFS{
+			"urls.yaml": {Data: []byte("news:\n  - https://b.example.com\n  - https://a.example.com\nblogs:\n  - https://c.example.com\nart:\n  - https://d.example.com\n")},
+		}
+
+		if err := l.CreateFeedsFromYaml(fs, "urls.yaml"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		got := l.Categories()
+		want := []string{"news", "blogs", "art"}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		feeds, _ := l.GetCategory("news")
+		if len(feeds) != 2 || feeds[0].Url != "https://b.example.com" {
+			t.Error("Feed order within category not kept")
+		}
+
+		l.SortCategories = true
+		got = l.Categories()
+		want = []string{"art", "blogs", "news"}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Should share feed between categories and tags", func(t *testing.T) {
+		l := NewListWithDefaults()
+		fs := fstest.MapFS{
+			"urls.yaml": {Data: []byte("golang:\n  - url: https://go.dev/blog/feed.atom\n    tags: [favourites]\nwork:\n  - https://go.dev/blog/feed.atom\n")},
+		}
+
+		if err := l.CreateFeedsFromYaml(fs, "urls.yaml"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if len(l.Feeds) != 2 {
+			t.Fatalf("Feed should be created once, got %d feeds", len(l.Feeds))
+		}
+
+		feed := l.FeedIndex["https://go.dev/blog/feed.atom"]
+		for _, category := range []string{"golang", "work", "favourites"} {
+			feeds, _ := l.GetCategory(category)
+			if len(feeds) != 1 || feeds[0] != feed {
+				t.Errorf("Feed not shared with %s", category)
+			}
+		}
+
+		if feed.Category != "golang" {
+			t.Errorf("First category should be primary, got %s", feed.Category)
+		}
+
+		want := []string{"golang", "work", "favourites"}
+		if got := l.Categories(); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		saved := &RssFeed{
+			Url:      feed.Url,
+			RssItems: []*RssItem{{Item: &gofeed.Item{GUID: "1"}, Read: true}},
+		}
+		var buf bytes.Buffer
+		prev := List{Feeds: []*RssFeed{saved}}
+		if err := prev.Save(&buf, time.Now()); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if err := l.Restore(&buf); err != nil {
+			t.Fatalf("Unexpected error restoring: %q", err)
+		}
+
+		work, _ := l.GetCategory("work")
+		if len(work[0].RssItems) != 1 || !work[0].RssItems[0].Read {
+			t.Error("Read state not shared between categories")
+		}
+	})
+
+	t.Run("Should add, move and remove feeds in place", func(t *testing.T) {
+		l := NewListWithDefaults()
+
+		feed := l.AddFeed("golang", FeedSettings{Url: "https://go.dev/blog/feed.atom", Tags: []string{"blogs"}})
+		if l.FeedIndex[feed.Url] != feed || len(l.CategoryIndex["golang"]) != 1 || len(l.CategoryIndex["blogs"]) != 1 {
+			t.Fatal("Feed not indexed")
+		}
+
+		if err := l.MoveFeed(feed.Url, "work"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if _, ok := l.CategoryIndex["golang"]; ok {
+			t.Error("Empty category not removed")
+		}
+		if feed.Category != "work" || len(l.CategoryIndex["work"]) != 1 || len(l.CategoryIndex["blogs"]) != 1 {
+			t.Error("Feed not moved")
+		}
+
+		item := &RssItem{Item: &gofeed.Item{GUID: "1"}}
+		feed.RssItems = append(feed.RssItems, item)
+		l.ReindexList()
+		l.SetBookmark(true, item)
+		if n := feed.BookmarkCount(); n != 1 {
+			t.Errorf("got %d bookmarks, want 1", n)
+		}
+
+		l.RemoveFeed(feed.Url)
+		if l.FeedIndex[feed.Url] != nil || len(l.CategoryIndex) != 0 || len(l.Feeds) != 1 {
+			t.Error("Feed not removed")
+		}
+		if l.ItemIndex["1"] != nil || len(l.Bookmarks().RssItems) != 0 {
+			t.Error("Items of removed feed kept")
+		}
+
+		assertError(t, l.MoveFeed(feed.Url, "work"), ErrFeedNotFound)
+	})
+
 	t.Run("Handle missing feeds file", func(t *testing.T) {
 		l := newList()
 
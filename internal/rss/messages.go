@@ -7,9 +7,20 @@ var (
 	ErrNoFeedsInList      = errors.New("no feeds in list")
 	ErrNoCategoryGiven    = errors.New("no category given")
 	ErrNoBookmarkFeed     = errors.New("no bookmark feed found")
-	ErrCooldown           = errors.New("5 second cooldown")
+	ErrBackoff            = errors.New("waiting to retry after failure")
+	ErrRefreshing         = errors.New("feed is already refreshing")
+	ErrUrlsFileFlowStyle  = errors.New("category in urls.yaml is not a block list, edit it by hand")
+	ErrFeedNotFound       = errors.New("feed not found")
+	ErrFeedExists         = errors.New("feed already in category")
+	ErrNoFeedFound        = errors.New("no feed found on page")
+	ErrHTMLPage           = errors.New("URL is a web page, not a feed")
+	ErrInvalidConfig      = errors.New("invalid config.yaml")
+	ErrSyncNotConfigured  = errors.New("no sync server set in config.yaml")
+	ErrDataFileRestored   = errors.New("could not read data.json, restored from")
+	ErrDataFileLocked     = errors.New("data.json is locked by another rssr, try again")
 	ErrConfigDoesNotExist = "open urls.yaml: file does not exist"
 	MsgFeedNotLoaded      = "Feed not loaded yet. Press shift+r"
+	UserAgent             = "rssr"
 	DefaultUrlsFile       = `# This file is written in YAML format.
 # Each feed must be organized under a category.
 # Feeds that are not assigned to a category will NOT appear in the app.
@@ -25,9 +36,124 @@ var (
 # Example (uncomment lines below to use):
 #feeds:
 #  - https://emilosman.com/feed
+#
+# Instead of a plain URL a feed can be written out with its own settings:
+#golang:
+#  - url: https://www.reddit.com/r/golang.rss
+#    title: r/golang
+#    interval: 30m
+#    headers:
+#      Cookie: over18=1
+#    tags: [reddit]
+#    filters: [hiring]
+#
+# A URL can be listed under several categories, and tags show the feed in
+# an extra tab. It is still one feed: read state and refreshes are shared.
 `
 	DefaultConfigFile = `# This file is written in YAML format.
-	# Below is the default config. Uncomment and change if needed.
-	# render_markdown: true
+# Below is the default config. Uncomment and change if needed.
+# Press shift+c in the app to edit it, changes apply when the editor closes.
+#
+# Render item content as markdown, or show it as plain text.
+#render_markdown: true
+#
+# Column item content wraps at. 0 wraps at the width of the window.
+#wrap_width: 80
+#
+# Colors of the app: auto, dark, light, mono or a theme defined below.
+# auto picks dark or light by the terminal background, or mono when
+# NO_COLOR is set.
+#theme: auto
+#
+# Themes start from a built in theme and change some of its colors. Colors
+# are ANSI color numbers or hex values. markdown is a glamour style name
+# (dark, light, notty, dracula, pink, tokyo-night...) or a style file.
+#themes:
+#  solarized:
+#    base: light
+#    unread: "#859900"
+#    bookmark: "#b58900"
+#    error: "#dc322f"
+#    title: "#d33682"
+#    status: "#93a1a1"
+#    tab: "#657b83"
+#    markdown: light
+#
+# Editor for urls.yaml and config.yaml. Defaults to $VISUAL, then $EDITOR.
+#editor: nvim
+#
+# Command links are opened with, the URL is added as the last argument.
+# Defaults to the browser of the system.
+#browser: firefox --new-tab
+#
+# Open links with other commands. Match on the URL (a regular expression),
+# the enclosure type, the feed (URL or title) or the category. The first
+# opener where everything set matches is used, other links go to the
+# browser. {url}, {title} and {feed} are replaced in the command, the URL is
+# added at the end when {url} is not in it. terminal: true gives the
+# terminal to the command until it exits.
+#openers:
+#  - match: ^https://(www\.)?youtube\.com/
+#    command: mpv
+#  - mime: application/pdf
+#    command: zathura {url}
+#  - mime: audio/*
+#    category: podcasts
+#    command: mpv --no-video
+#    terminal: true
+#
+# Rewrite links before they are opened. Built in rules: old_reddit, xcancel
+# and strip_tracking. Rules replace what match matches, $1 is the first
+# group, and strip removes query parameters, utm_* removes every parameter
+# starting with utm_. on_store rewrites links as feeds are fetched too, so
+# stored links stay the same.
+#rewrite:
+#  builtin: [old_reddit, strip_tracking]
+#  rules:
+#    - match: ^https://(www\.)?youtube\.com/watch
+#      replace: https://yewtu.be/watch
+#    - match: ^https://medium\.com/
+#      strip: [source]
+#  on_store: false
+#
+# Show categories alphabetically instead of in the order of urls.yaml.
+#sort_categories: false
+#
+# Refresh feeds in the background while rssr is open.
+# The most specific interval wins: feeds, then categories, then interval.
+#refresh:
+#  interval: 1h
+#  categories:
+#    golang: 30m
+#  feeds:
+#    https://emilosman.com/feed: 6h
+#
+# Items kept per feed, everything is kept by default. max_items keeps the
+# newest items, max_age drops older ones and keep_unread keeps unread items
+# either way. Bookmarked items are always kept. Feeds are pruned as they are
+# refreshed and on start, rssr gc prunes all of them.
+#retention:
+#  max_items: 200
+#  max_age: 720h
+#  keep_unread: false
+#
+# Server read and bookmark state is synced with.
+#sync:
+#  url: https://rssr.example.com
+#  api_key: secret
+#
+# Where feeds, items and read state are kept in the cache dir: json keeps
+# them in data.json, sqlite in rssr.db, which only writes what changed and
+# takes over data.json the first time. Restart rssr after changing it.
+#storage: json
+#
+# Change the keys of actions per view: feeds, items and item. Keys given
+# replace the defaults of the action. See README.md for all action names.
+#keys:
+#  feeds:
+#    next_unread: j
+#    prev_unread: [k, shift+k]
+#  items:
+#    toggle_bookmark: x
 `
 )
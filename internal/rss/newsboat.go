@@ -0,0 +1,172 @@
+package rss
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportWarning is a line of an import that was left out.
+type ImportWarning struct {
+	Line   int
+	Text   string
+	Reason string
+}
+
+func (w ImportWarning) String() string {
+	return fmt.Sprintf("line %d: %s: %s", w.Line, w.Reason, w.Text)
+}
+
+// ReadNewsboatUrls reads a newsboat urls file. The first tag of a feed is
+// its category and the rest become tags, "~Title" sets the title. Query,
+// exec and filter feeds have no equivalent and are returned as warnings.
+func ReadNewsboatUrls(r io.Reader) ([]Subscription, []ImportWarning, error) {
+	var subs []Subscription
+	var warnings []ImportWarning
+
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := splitNewsboatLine(line)
+		u := fields[0]
+
+		if reason := newsboatUnsupported(u); reason != "" {
+			warnings = append(warnings, ImportWarning{Line: n, Text: line, Reason: reason})
+			continue
+		}
+
+		sub := Subscription{Category: ImportCategory, Settings: FeedSettings{Url: u}}
+		var tags []string
+		for _, tag := range fields[1:] {
+			switch {
+			case strings.HasPrefix(tag, "~"):
+				sub.Settings.Title = strings.TrimPrefix(tag, "~")
+			case strings.HasPrefix(tag, "!"):
+				// Hidden feeds are shown in rssr, the marker is dropped
+			case tag != "":
+				tags = append(tags, tag)
+			}
+		}
+
+		if len(tags) > 0 {
+			sub.Category = tags[0]
+			sub.Settings.Tags = tags[1:]
+		}
+
+		subs = append(subs, sub)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return subs, warnings, nil
+}
+
+// NewsboatUrlsPath returns the newsboat urls file that exists, preferring
+// the XDG location newsboat itself checks first.
+func NewsboatUrlsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(home, ".config")
+	}
+
+	paths := []string{
+		filepath.Join(dir, "newsboat", "urls"),
+		filepath.Join(home, ".newsboat", "urls"),
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return paths[len(paths)-1]
+}
+
+// NewsboatCachePath returns the newsboat cache.db that exists, preferring
+// the XDG location.
+func NewsboatCachePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		dir = filepath.Join(home, ".local", "share")
+	}
+
+	paths := []string{
+		filepath.Join(dir, "newsboat", "cache.db"),
+		filepath.Join(home, ".newsboat", "cache.db"),
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return paths[len(paths)-1]
+}
+
+func newsboatUnsupported(u string) string {
+	switch {
+	case strings.HasPrefix(u, "query:"):
+		return "query feeds are not supported"
+	case strings.HasPrefix(u, "exec:"):
+		return "exec feeds are not supported"
+	case strings.HasPrefix(u, "filter:"):
+		return "filter feeds are not supported"
+	case !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://"):
+		return "not an http(s) URL"
+	}
+	return ""
+}
+
+// splitNewsboatLine splits on spaces outside of double quotes and removes
+// the quotes. A backslash escapes the next character inside quotes.
+func splitNewsboatLine(line string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted, escaped, started := false, false, false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if started {
+				fields = append(fields, field.String())
+				field.Reset()
+				started = false
+			}
+		default:
+			field.WriteRune(r)
+			started = true
+		}
+	}
+
+	if started {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
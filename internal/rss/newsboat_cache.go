@@ -0,0 +1,91 @@
+package rss
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// NewsboatStarFlags are the newsboat flags that mark an item as starred
+// when no others are given. Newsboat has no fixed flag for it, "s" is the
+// common choice.
+const NewsboatStarFlags = "s"
+
+// CacheImport counts what ImportNewsboatCache did.
+type CacheImport struct {
+	// Items in the cache that matched an item in the list
+	Matched int
+	// Items in the cache that are not in the list, mostly ones that are
+	// no longer in the feed
+	Unmatched int
+	Read      int
+	Starred   int
+}
+
+// ImportNewsboatCache copies the read state and stars from a newsboat
+// cache.db onto the items already in the list, matched by GUID and then by
+// link. Items are only marked read or starred, never the other way round.
+func ImportNewsboatCache(l *List, path, starFlags string, now time.Time) (CacheImport, error) {
+	var res CacheImport
+
+	db, err := sql.Open("sqlite", "file:"+(&url.URL{Path: path}).EscapedPath()+"?mode=ro")
+	if err != nil {
+		return res, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT guid, url, unread, flags FROM rss_item")
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+
+	l.ReindexList()
+	links := make(map[string]*RssItem, len(l.ItemIndex))
+	for _, item := range l.ItemIndex {
+		if link := item.Link(); link != "" {
+			links[link] = item
+		}
+	}
+
+	for rows.Next() {
+		var guid, link, flags sql.NullString
+		var unread bool
+		if err := rows.Scan(&guid, &link, &unread, &flags); err != nil {
+			return res, err
+		}
+
+		item := l.ItemIndex[guid.String]
+		if item == nil {
+			item = l.ItemIndex[link.String]
+		}
+		if item == nil {
+			item = links[link.String]
+		}
+		if item == nil {
+			res.Unmatched++
+			continue
+		}
+		res.Matched++
+
+		if !unread && !item.Read {
+			item.Read = true
+			item.Ts = now.UnixNano()
+			res.Read++
+		}
+
+		if starFlags != "" && strings.ContainsAny(flags.String, starFlags) && !item.Bookmark {
+			item.Bookmark = true
+			item.Ts = now.UnixNano()
+			if err := l.SetBookmark(true, item); err != nil {
+				return res, err
+			}
+			res.Starred++
+		}
+	}
+
+	return res, rows.Err()
+}
@@ -0,0 +1,111 @@
+package rss
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func newsboatCache(t *testing.T, rows [][]any) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cache.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %q", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE rss_item (
+		id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+		guid VARCHAR(64) NOT NULL,
+		title VARCHAR(1024) NOT NULL,
+		url VARCHAR(1024) NOT NULL,
+		feedurl VARCHAR(1024) NOT NULL,
+		unread INTEGER(1) NOT NULL,
+		flags VARCHAR(52),
+		deleted INTEGER(1) NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %q", err)
+	}
+
+	for _, row := range rows {
+		_, err := db.Exec(`INSERT INTO rss_item (guid, title, url, feedurl, unread, flags)
+			VALUES (?, '', ?, 'https://example.com/feed', ?, ?)`, row...)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+	}
+
+	return path
+}
+
+func TestNewsboatCache(t *testing.T) {
+	t.Run("Should copy read and starred state", func(t *testing.T) {
+		byGuid := &RssItem{Item: &gofeed.Item{GUID: "guid-1", Link: "https://example.com/1"}}
+		byLink := &RssItem{Item: &gofeed.Item{GUID: "other", Link: "https://example.com/2"}}
+		starred := &RssItem{Item: &gofeed.Item{GUID: "guid-3"}}
+		unread := &RssItem{Item: &gofeed.Item{GUID: "guid-4"}}
+
+		l := NewListWithDefaults()
+		l.Add(&RssFeed{
+			Url:      "https://example.com/feed",
+			Category: "news",
+			RssItems: []*RssItem{byGuid, byLink, starred, unread},
+		})
+
+		path := newsboatCache(t, [][]any{
+			{"guid-1", "https://example.com/1", 0, nil},
+			{"https://example.com/2", "https://example.com/2", 0, ""},
+			{"guid-3", "https://example.com/3", 1, "as"},
+			{"guid-4", "https://example.com/4", 1, nil},
+			{"gone", "https://example.com/gone", 0, nil},
+		})
+
+		now := time.Now()
+		res, err := ImportNewsboatCache(l, path, NewsboatStarFlags, now)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := CacheImport{Matched: 4, Unmatched: 1, Read: 2, Starred: 1}
+		if res != want {
+			t.Errorf("got %+v, want %+v", res, want)
+		}
+
+		if !byGuid.Read || !byLink.Read {
+			t.Error("Read state not copied")
+		}
+		if byGuid.Ts != now.UnixNano() {
+			t.Error("Timestamp not set on changed item")
+		}
+		if starred.Read || !starred.Bookmark {
+			t.Error("Starred state not copied")
+		}
+		if unread.Read || unread.Ts != 0 {
+			t.Error("Unread item changed")
+		}
+		if len(l.Bookmarks().RssItems) != 1 {
+			t.Error("Starred item not added to bookmarks")
+		}
+	})
+
+	t.Run("Should report missing rss_item table", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache.db")
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		db.Exec("CREATE TABLE other (id INTEGER)")
+		db.Close()
+
+		_, err = ImportNewsboatCache(NewListWithDefaults(), path, NewsboatStarFlags, time.Now())
+		if err == nil {
+			t.Error("Should return error")
+		}
+	})
+}
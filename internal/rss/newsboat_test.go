@@ -0,0 +1,51 @@
+package rss
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestNewsboat(t *testing.T) {
+	t.Run("Should read newsboat urls", func(t *testing.T) {
+		subs, warnings, err := ReadNewsboatUrls(bytes.NewReader(testData(t, "newsboat_urls")))
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := []Subscription{
+			{"golang", FeedSettings{Url: "https://go.dev/blog/feed.atom"}},
+			{"golang", FeedSettings{Url: "https://www.reddit.com/r/golang.rss", Title: "r/golang", Tags: []string{"reddit"}}},
+			{ImportCategory, FeedSettings{Url: "https://news.ycombinator.com/rss", Title: "Hacker News"}},
+			{"news", FeedSettings{Url: "https://example.com/hidden.xml"}},
+		}
+
+		if len(subs) != len(want) {
+			t.Fatalf("got %d subscriptions, want %d", len(subs), len(want))
+		}
+
+		for i := range want {
+			got := subs[i]
+			if got.Category != want[i].Category || got.Settings.Url != want[i].Settings.Url ||
+				got.Settings.Title != want[i].Settings.Title || !slices.Equal(got.Settings.Tags, want[i].Settings.Tags) {
+				t.Errorf("got %+v, want %+v", got, want[i])
+			}
+		}
+
+		lines := []int{}
+		for _, w := range warnings {
+			lines = append(lines, w.Line)
+		}
+		if !slices.Equal(lines, []int{6, 7, 8}) {
+			t.Errorf("got warnings on lines %v, want [6 7 8]", lines)
+		}
+	})
+
+	t.Run("Should split quoted fields", func(t *testing.T) {
+		got := splitNewsboatLine(`https://example.com "two words" "say \"hi\"" plain`)
+		want := []string{"https://example.com", "two words", `say "hi"`, "plain"}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
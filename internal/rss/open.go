@@ -0,0 +1,142 @@
+package rss
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Opener runs a command for the links it matches. Every match field that is
+// set has to match. The command is split on spaces, {url}, {title} and
+// {feed} in it are replaced and the URL is added at the end when {url} is
+// not in it.
+type Opener struct {
+	// Match is a regular expression for the URL
+	Match string `yaml:"match"`
+	// Mime is the type of an enclosure, like audio/* or application/pdf
+	Mime string `yaml:"mime"`
+	// Feed is the URL or the title of the feed
+	Feed     string `yaml:"feed"`
+	Category string `yaml:"category"`
+	Command  string `yaml:"command"`
+	// Terminal hands the terminal to the command until it exits
+	Terminal bool `yaml:"terminal"`
+}
+
+// Link is a URL to open, with what openers match on.
+type Link struct {
+	Url   string
+	Mime  string
+	Title string
+	Feed  *RssFeed
+}
+
+// OpenCommand returns the command that opens the link after rewriting it:
+// the first opener that matches it, or the browser. terminal reports
+// whether the command needs the terminal while it runs.
+func (c *Config) OpenCommand(link Link) (cmd *exec.Cmd, terminal bool, err error) {
+	parsed, err := url.ParseRequestURI(c.Rewrite.Rewriter().Apply(link.Url))
+	if err != nil {
+		return nil, false, err
+	}
+	link.Url = parsed.String()
+
+	for _, o := range c.Openers {
+		if o.matches(link) {
+			return o.command(link), o.Terminal, nil
+		}
+	}
+
+	cmd, err = browserCommand(c.Browser, link.Url)
+	return cmd, false, err
+}
+
+func (o Opener) matches(link Link) bool {
+	if o.Match != "" {
+		re, err := regexp.Compile(o.Match)
+		if err != nil || !re.MatchString(link.Url) {
+			return false
+		}
+	}
+
+	if o.Mime != "" {
+		if ok, err := path.Match(o.Mime, link.Mime); err != nil || !ok {
+			return false
+		}
+	}
+
+	if o.Feed != "" {
+		if link.Feed == nil || (o.Feed != link.Feed.Url && o.Feed != link.Feed.Name()) {
+			return false
+		}
+	}
+
+	if o.Category != "" {
+		if link.Feed == nil || !slices.Contains(link.Feed.categories(), o.Category) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (o Opener) command(link Link) *exec.Cmd {
+	feed := ""
+	if link.Feed != nil {
+		feed = link.Feed.Name()
+	}
+
+	r := strings.NewReplacer("{url}", link.Url, "{title}", link.Title, "{feed}", feed)
+
+	fields := strings.Fields(o.Command)
+	args := make([]string, 0, len(fields))
+	for _, f := range fields[1:] {
+		args = append(args, r.Replace(f))
+	}
+	if !strings.Contains(o.Command, "{url}") {
+		args = append(args, link.Url)
+	}
+
+	return exec.Command(fields[0], args...)
+}
+
+// validateOpener lists what is wrong with the opener at index i.
+func validateOpener(i int, o Opener) []string {
+	var problems []string
+
+	if len(strings.Fields(o.Command)) == 0 {
+		problems = append(problems, fmt.Sprintf("openers[%d].command is empty", i))
+	}
+
+	if _, err := regexp.Compile(o.Match); err != nil {
+		problems = append(problems, fmt.Sprintf("openers[%d].match is not a valid regular expression: %v", i, err))
+	}
+
+	if _, err := path.Match(o.Mime, ""); err != nil {
+		problems = append(problems, fmt.Sprintf("openers[%d].mime is not a valid pattern, got %q", i, o.Mime))
+	}
+
+	return problems
+}
+
+func browserCommand(browser, url string) (*exec.Cmd, error) {
+	if fields := strings.Fields(browser); len(fields) > 0 {
+		return exec.Command(fields[0], append(fields[1:], url)...), nil
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url), nil
+	case "linux":
+		return exec.Command("xdg-open", url), nil
+	case "windows":
+		return exec.Command("cmd", "/c", "start", "", url), nil
+	default:
+		return nil, fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+}
@@ -0,0 +1,75 @@
+package rss
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOpeners(t *testing.T) {
+	feed := &RssFeed{Url: "https://example.com/feed", Category: "podcasts", Settings: FeedSettings{Title: "Example"}}
+	c := DefaultConfig()
+	c.Browser = "firefox --new-tab"
+	c.Openers = []Opener{
+		{Match: `^https://(www\.)?youtube\.com/`, Command: "mpv --no-terminal"},
+		{Mime: "application/pdf", Command: "zathura {url}"},
+		{Mime: "audio/*", Category: "podcasts", Command: "mpv --title={title} {url}", Terminal: true},
+		{Feed: "Example", Command: "lynx"},
+	}
+
+	tests := []struct {
+		name     string
+		link     Link
+		args     []string
+		terminal bool
+	}{
+		{"url", Link{Url: "https://youtube.com/watch?v=1"}, []string{"mpv", "--no-terminal", "https://youtube.com/watch?v=1"}, false},
+		{"mime", Link{Url: "https://example.com/a.pdf", Mime: "application/pdf"}, []string{"zathura", "https://example.com/a.pdf"}, false},
+		{"mime and category", Link{Url: "https://example.com/1.mp3", Mime: "audio/mpeg", Title: "Episode", Feed: feed}, []string{"mpv", "--title=Episode", "https://example.com/1.mp3"}, true},
+		{"feed", Link{Url: "https://example.com/post", Feed: feed}, []string{"lynx", "https://example.com/post"}, false},
+		{"first match wins", Link{Url: "https://www.youtube.com/watch?v=2", Feed: feed}, []string{"mpv", "--no-terminal", "https://www.youtube.com/watch?v=2"}, false},
+		{"browser", Link{Url: "https://go.dev/blog"}, []string{"firefox", "--new-tab", "https://go.dev/blog"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, terminal, err := c.OpenCommand(tt.link)
+			if err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+			if !slices.Equal(cmd.Args, tt.args) {
+				t.Errorf("got %q, want %q", cmd.Args, tt.args)
+			}
+			if terminal != tt.terminal {
+				t.Errorf("got terminal %v, want %v", terminal, tt.terminal)
+			}
+		})
+	}
+
+	t.Run("Should not open invalid URL", func(t *testing.T) {
+		if _, _, err := c.OpenCommand(Link{Url: "not a url"}); err == nil {
+			t.Error("Should return error for invalid URL")
+		}
+	})
+
+	t.Run("Should report invalid openers", func(t *testing.T) {
+		c := DefaultConfig()
+		c.Openers = []Opener{
+			{Match: "(", Command: "mpv"},
+			{Mime: "[", Command: "mpv"},
+			{Match: "pdf$"},
+		}
+
+		err := c.Validate()
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("got %q want %q", err, ErrInvalidConfig)
+		}
+
+		for _, want := range []string{"openers[0].match", "openers[1].mime", "openers[2].command is empty"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Error should contain %s, got %q", want, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package rss
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+type opml struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Category string        `xml:"category,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// ReadOPML reads the subscriptions of an OPML file. Folders become
+// categories, nested folders use the innermost name.
+func ReadOPML(r io.Reader) ([]Subscription, error) {
+	var doc opml
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var subs []Subscription
+	var walk func(outlines []opmlOutline, category string)
+	walk = func(outlines []opmlOutline, category string) {
+		for _, o := range outlines {
+			if o.XMLURL == "" {
+				name := o.Text
+				if name == "" {
+					name = o.Title
+				}
+				if name == "" {
+					name = category
+				}
+				walk(o.Outlines, name)
+				continue
+			}
+
+			title := o.Title
+			if title == "" {
+				title = o.Text
+			}
+
+			subs = append(subs, Subscription{
+				Category: category,
+				Settings: FeedSettings{
+					Url:   strings.TrimSpace(o.XMLURL),
+					Title: title,
+					Tags:  opmlTags(o.Category),
+				},
+			})
+		}
+	}
+	walk(doc.Body.Outlines, ImportCategory)
+
+	return subs, nil
+}
+
+// WriteOPML writes the feeds of the list as OPML 2.0, one folder per
+// category in urls.yaml. Tags are kept in the category attribute.
+func (l *List) WriteOPML(w io.Writer, now time.Time) error {
+	doc := opml{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       "rssr",
+			DateCreated: now.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	categories := l.CategoryOrder
+	if len(categories) == 0 {
+		categories = l.Categories()
+	}
+
+	for _, category := range categories {
+		folder := opmlOutline{Text: category, Title: category}
+		for _, feed := range l.CategoryIndex[category] {
+			outline := opmlOutline{
+				Text:     feed.Name(),
+				Title:    feed.Name(),
+				Type:     "rss",
+				XMLURL:   feed.Url,
+				Category: strings.Join(feed.Settings.Tags, ","),
+			}
+			if feed.Feed != nil {
+				outline.HTMLURL = feed.Feed.Link
+			}
+			folder.Outlines = append(folder.Outlines, outline)
+		}
+		doc.Body.Outlines = append(doc.Body.Outlines, folder)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// opmlTags splits the comma separated category attribute. Categories are
+// often written as paths, only the last part is kept.
+func opmlTags(attr string) []string {
+	var tags []string
+	for _, c := range strings.Split(attr, ",") {
+		c = strings.Trim(strings.TrimSpace(c), "/")
+		if i := strings.LastIndex(c, "/"); i != -1 {
+			c = c[i+1:]
+		}
+		if c != "" {
+			tags = append(tags, c)
+		}
+	}
+	return tags
+}
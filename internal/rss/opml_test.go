@@ -0,0 +1,78 @@
+package rss
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestOPML(t *testing.T) {
+	t.Run("Should read OPML folders as categories", func(t *testing.T) {
+		subs, err := ReadOPML(bytes.NewReader(testData(t, "feeds.opml")))
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := []Subscription{
+			{ImportCategory, FeedSettings{Url: "https://example.com/top.xml", Title: "Uncategorized feed"}},
+			{"golang", FeedSettings{Url: "https://go.dev/blog/feed.atom", Title: "The Go Blog"}},
+			{"golang", FeedSettings{Url: "https://www.reddit.com/r/golang.rss", Title: "r/golang", Tags: []string{"reddit", "news"}}},
+			{"projects", FeedSettings{Url: "https://status.example.com/feed", Title: "Status"}},
+		}
+
+		if len(subs) != len(want) {
+			t.Fatalf("got %d subscriptions, want %d", len(subs), len(want))
+		}
+
+		for i := range want {
+			got := subs[i]
+			if got.Category != want[i].Category || got.Settings.Url != want[i].Settings.Url ||
+				got.Settings.Title != want[i].Settings.Title || !slices.Equal(got.Settings.Tags, want[i].Settings.Tags) {
+				t.Errorf("got %+v, want %+v", got, want[i])
+			}
+		}
+	})
+
+	t.Run("Should handle invalid OPML", func(t *testing.T) {
+		_, err := ReadOPML(strings.NewReader("<opml><body>"))
+		if err == nil {
+			t.Error("Should return error")
+		}
+	})
+
+	t.Run("Should export and import the same feeds", func(t *testing.T) {
+		l := NewListWithDefaults()
+		fs := fstest.MapFS{
+			"urls.yaml": {Data: []byte("golang:\n  - url: https://go.dev/blog/feed.atom\n    title: Go & friends\n    tags: [news]\njobs:\n  - https://golang.cafe/rss\n")},
+		}
+		if err := l.CreateFeedsFromYaml(fs, "urls.yaml"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		var buf bytes.Buffer
+		if err := l.WriteOPML(&buf, time.Now()); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		subs, err := ReadOPML(&buf)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if len(subs) != 2 {
+			t.Fatalf("got %d subscriptions, want 2", len(subs))
+		}
+
+		if subs[0].Category != "golang" || subs[0].Settings.Title != "Go & friends" ||
+			!slices.Equal(subs[0].Settings.Tags, []string{"news"}) {
+			t.Errorf("Feed not exported: %+v", subs[0])
+		}
+
+		if subs[1].Category != "jobs" || subs[1].Settings.Url != "https://golang.cafe/rss" {
+			t.Errorf("Feed not exported: %+v", subs[1])
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package rss
+
+import (
+	"slices"
+	"time"
+)
+
+// RetentionConfig limits the items kept per feed. Zero values keep
+// everything. Bookmarked items are always kept.
+type RetentionConfig struct {
+	// MaxItems is how many items are kept per feed, newest first
+	MaxItems int `yaml:"max_items"`
+	// MaxAge drops items published longer ago. Items without a date are
+	// only dropped by MaxItems
+	MaxAge time.Duration `yaml:"max_age"`
+	// KeepUnread keeps unread items however many or old they are
+	KeepUnread bool `yaml:"keep_unread"`
+}
+
+func (r RetentionConfig) Enabled() bool {
+	return r.MaxItems > 0 || r.MaxAge > 0
+}
+
+// Prune removes the items of f that r does not keep and returns how many it
+// removed. Items are counted newest first by date, whatever the order of
+// the feed, undated items last. Kept bookmarked and unread items count
+// towards MaxItems. Pruned items are remembered so refreshes and merges do
+// not add them back.
+func (r RetentionConfig) Prune(f *RssFeed, now time.Time) int {
+	if !r.Enabled() || f.Url == "Bookmarks" {
+		return 0
+	}
+
+	newest := slices.Clone(f.RssItems)
+	slices.SortStableFunc(newest, newestFirst)
+
+	n := 0
+	dropped := make(map[*RssItem]bool)
+	for _, item := range newest {
+		if !r.keeps(item, n, now) {
+			dropped[item] = true
+			continue
+		}
+		n++
+	}
+
+	f.RssItems = slices.DeleteFunc(slices.Clone(f.RssItems), func(item *RssItem) bool {
+		if dropped[item] {
+			f.forget(item.GUID())
+		}
+		return dropped[item]
+	})
+	return len(dropped)
+}
+
+// newestFirst orders items by date, newest first, with undated items last.
+func newestFirst(a, b *RssItem) int {
+	ta, tb := a.Timestamp(), b.Timestamp()
+	switch {
+	case ta == nil && tb == nil:
+		return 0
+	case ta == nil:
+		return 1
+	case tb == nil:
+		return -1
+	}
+	return tb.Compare(*ta)
+}
+
+// keeps reports whether item stays when n items before it were kept.
+func (r RetentionConfig) keeps(item *RssItem, n int, now time.Time) bool {
+	if item.Item == nil || item.Bookmark || (r.KeepUnread && !item.Read) {
+		return true
+	}
+	if r.MaxItems > 0 && n >= r.MaxItems {
+		return false
+	}
+	if ts := item.Timestamp(); r.MaxAge > 0 && ts != nil && now.Sub(*ts) > r.MaxAge {
+		return false
+	}
+	return true
+}
+
+// Prune prunes every feed of l by r and returns how many items it removed
+// from each feed, by URL. Feeds with nothing pruned are left out.
+func (l *List) Prune(r RetentionConfig, now time.Time) map[string]int {
+	pruned := make(map[string]int)
+	for _, feed := range l.Feeds {
+		before := feed.RssItems
+		if n := r.Prune(feed, now); n > 0 {
+			pruned[feed.Url] = n
+			l.unindexPruned(feed, before)
+		}
+	}
+	return pruned
+}
+
+// forget remembers the key of a pruned item.
+func (f *RssFeed) forget(key string) {
+	if f.Pruned == nil {
+		f.Pruned = make(map[string]bool)
+	}
+	f.Pruned[key] = true
+}
+
+// dropPruned removes the items of f under keys another instance pruned,
+// and remembers their keys. Bookmarked items stay.
+func (l *List) dropPruned(f *RssFeed, keys map[string]bool) {
+	before := f.RssItems
+	f.RssItems = slices.DeleteFunc(slices.Clone(before), func(item *RssItem) bool {
+		if item.Item == nil || item.Bookmark || !keys[item.GUID()] {
+			return false
+		}
+		f.forget(item.GUID())
+		return true
+	})
+	l.unindexPruned(f, before)
+}
+
+// unindexPruned removes the items that were in before and are no longer in
+// f from the item index.
+func (l *List) unindexPruned(f *RssFeed, before []*RssItem) {
+	kept := make(map[*RssItem]bool, len(f.RssItems))
+	for _, item := range f.RssItems {
+		kept[item] = true
+	}
+	for _, item := range before {
+		if item.Item != nil && !kept[item] && l.ItemIndex[item.GUID()] == item {
+			delete(l.ItemIndex, item.GUID())
+		}
+	}
+}
+
+// forgetGone drops the remembered keys of pruned items the feed no longer
+// lists, they cannot come back.
+func (f *RssFeed) forgetGone(fetched map[string]struct{}) {
+	for key := range f.Pruned {
+		if _, ok := fetched[key]; !ok {
+			delete(f.Pruned, key)
+		}
+	}
+}
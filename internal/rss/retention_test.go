@@ -0,0 +1,151 @@
+package rss
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestRetention(t *testing.T) {
+	now := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	item := func(guid string, age time.Duration) *gofeed.Item {
+		published := now.Add(-age)
+		return &gofeed.Item{GUID: guid, Title: guid, Published: published.Format(time.RFC3339), PublishedParsed: &published}
+	}
+
+	newFeed := func(items ...*gofeed.Item) *RssFeed {
+		f := &RssFeed{Url: "https://example.com/feed"}
+		for _, i := range items {
+			f.RssItems = append(f.RssItems, &RssItem{Item: i, Read: true})
+		}
+		return f
+	}
+
+	guids := func(f *RssFeed) []string {
+		var guids []string
+		for _, i := range f.RssItems {
+			guids = append(guids, i.GUID())
+		}
+		return guids
+	}
+
+	t.Run("Should keep everything by default", func(t *testing.T) {
+		f := newFeed(item("1", time.Hour), item("2", 1000*24*time.Hour))
+
+		if n := (RetentionConfig{}).Prune(f, now); n != 0 || len(f.RssItems) != 2 {
+			t.Errorf("got %d pruned, %v kept", n, guids(f))
+		}
+	})
+
+	t.Run("Should keep the newest max_items", func(t *testing.T) {
+		f := newFeed(item("1", time.Hour), item("2", 2*time.Hour), item("3", 3*time.Hour), item("4", 4*time.Hour))
+
+		n := RetentionConfig{MaxItems: 2}.Prune(f, now)
+		if got := guids(f); n != 2 || len(got) != 2 || got[0] != "1" || got[1] != "2" {
+			t.Errorf("got %d pruned, %v kept", n, got)
+		}
+		if !f.Pruned["3"] || !f.Pruned["4"] {
+			t.Errorf("Pruned items not remembered: %v", f.Pruned)
+		}
+	})
+
+	t.Run("Should keep the newest max_items whatever the order", func(t *testing.T) {
+		f := newFeed(item("3", 3*time.Hour), item("2", 2*time.Hour), item("1", time.Hour))
+
+		n := RetentionConfig{MaxItems: 1}.Prune(f, now)
+		if got := guids(f); n != 2 || len(got) != 1 || got[0] != "1" {
+			t.Errorf("got %d pruned, %v kept", n, got)
+		}
+
+		f = newFeed(item("3", 3*time.Hour), item("2", 2*time.Hour), item("1", time.Hour))
+		f.RssItems[1].Read = false
+		RetentionConfig{MaxItems: 1, KeepUnread: true}.Prune(f, now)
+		if got := guids(f); len(got) != 2 || got[0] != "2" || got[1] != "1" {
+			t.Errorf("got %v kept", got)
+		}
+	})
+
+	t.Run("Should drop items older than max_age", func(t *testing.T) {
+		f := newFeed(item("1", time.Hour), item("2", 48*time.Hour))
+		f.RssItems = append(f.RssItems, &RssItem{Item: &gofeed.Item{GUID: "undated"}, Read: true})
+
+		RetentionConfig{MaxAge: 24 * time.Hour}.Prune(f, now)
+		if got := guids(f); len(got) != 2 || got[0] != "1" || got[1] != "undated" {
+			t.Errorf("got %v kept", got)
+		}
+	})
+
+	t.Run("Should never drop bookmarked items", func(t *testing.T) {
+		f := newFeed(item("1", time.Hour), item("2", 48*time.Hour), item("3", 72*time.Hour))
+		f.RssItems[2].Bookmark = true
+
+		RetentionConfig{MaxItems: 1, MaxAge: time.Hour / 2}.Prune(f, now)
+		if got := guids(f); len(got) != 1 || got[0] != "3" {
+			t.Errorf("got %v kept", got)
+		}
+	})
+
+	t.Run("Should keep unread items with keep_unread", func(t *testing.T) {
+		f := newFeed(item("1", time.Hour), item("2", 48*time.Hour), item("3", 72*time.Hour))
+		f.RssItems[1].Read = false
+
+		RetentionConfig{MaxItems: 1, KeepUnread: true}.Prune(f, now)
+		if got := guids(f); len(got) != 2 || got[0] != "1" || got[1] != "2" {
+			t.Errorf("got %v kept", got)
+		}
+	})
+
+	t.Run("Should not add pruned items back on refresh", func(t *testing.T) {
+		f := newFeed(item("1", time.Hour), item("2", 2*time.Hour))
+		RetentionConfig{MaxItems: 1}.Prune(f, now)
+
+		f.mergeItems([]*gofeed.Item{item("0", 0), item("1", time.Hour), item("2", 2*time.Hour)}, nil)
+		if got := guids(f); len(got) != 2 || got[0] != "1" || got[1] != "0" {
+			t.Errorf("got %v", got)
+		}
+
+		// Gone from the feed, it can be forgotten
+		f.mergeItems([]*gofeed.Item{item("0", 0)}, nil)
+		if len(f.Pruned) != 0 {
+			t.Errorf("Expected pruned keys to be dropped, got %v", f.Pruned)
+		}
+	})
+
+	t.Run("Should prune every feed of a list and unindex the items", func(t *testing.T) {
+		l := NewListWithDefaults()
+		l.AddFeed("golang", FeedSettings{Url: "https://example.com/a"})
+		l.AddFeed("golang", FeedSettings{Url: "https://example.com/b"})
+		l.FeedIndex["https://example.com/a"].RssItems = newFeed(item("a1", time.Hour), item("a2", 2*time.Hour)).RssItems
+		l.FeedIndex["https://example.com/b"].RssItems = newFeed(item("b1", time.Hour)).RssItems
+		l.ReindexList()
+
+		pruned := l.Prune(RetentionConfig{MaxItems: 1}, now)
+		if len(pruned) != 1 || pruned["https://example.com/a"] != 1 {
+			t.Errorf("got %v", pruned)
+		}
+		if l.ItemIndex["a2"] != nil || l.ItemIndex["a1"] == nil {
+			t.Error("Pruned item should be removed from the index")
+		}
+	})
+
+	t.Run("Should drop items another instance pruned from data.json", func(t *testing.T) {
+		l := NewListWithDefaults()
+		l.AddFeed("golang", FeedSettings{Url: "https://example.com/feed"})
+		feed := l.FeedIndex["https://example.com/feed"]
+		feed.RssItems = newFeed(item("1", time.Hour), item("2", 2*time.Hour), item("3", 3*time.Hour)).RssItems
+		l.SetBookmark(true, feed.RssItems[2])
+		l.ReindexList()
+
+		saved := &RssFeed{Url: feed.Url, RssItems: []*RssItem{feed.RssItems[0]}, Pruned: map[string]bool{"2": true, "3": true}}
+		l.mergeFeeds([]*RssFeed{saved})
+
+		if got := guids(feed); len(got) != 2 || got[0] != "1" || got[1] != "3" {
+			t.Errorf("got %v", got)
+		}
+		if !feed.Pruned["2"] || feed.Pruned["3"] {
+			t.Errorf("got pruned %v", feed.Pruned)
+		}
+	})
+}
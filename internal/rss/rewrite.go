@@ -0,0 +1,187 @@
+package rss
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// RewriteConfig changes links before they are opened. Built in rules run
+// first, then the rules from config.yaml, each on the result of the last.
+type RewriteConfig struct {
+	Builtin []string      `yaml:"builtin"`
+	Rules   []RewriteRule `yaml:"rules"`
+	// OnStore rewrites item links as feeds are fetched as well, so stored
+	// links and the keys items are deduplicated and synced by stay stable
+	OnStore bool `yaml:"on_store"`
+}
+
+// RewriteRule replaces the part of a URL that Match matches with Replace,
+// which can use $1 style groups. Strip removes query parameters, a trailing
+// * matches every parameter starting with the rest. With Match set, Strip
+// only applies to the URLs it matches.
+type RewriteRule struct {
+	Match   string   `yaml:"match"`
+	Replace string   `yaml:"replace"`
+	Strip   []string `yaml:"strip"`
+}
+
+// BuiltinRewrites can be turned on by name under rewrite.builtin.
+var BuiltinRewrites = map[string][]RewriteRule{
+	"old_reddit": {{
+		Match:   `^https?://(www\.|new\.)?reddit\.com/`,
+		Replace: "https://old.reddit.com/",
+	}},
+	"xcancel": {{
+		Match:   `^https?://(www\.|mobile\.)?(twitter|x)\.com/`,
+		Replace: "https://xcancel.com/",
+	}},
+	"strip_tracking": {{
+		Strip: []string{"utm_*", "fbclid", "gclid", "mc_cid", "mc_eid", "ref"},
+	}},
+}
+
+// Rewriter applies the rewrite rules of the config. A nil Rewriter leaves
+// URLs as they are.
+type Rewriter struct {
+	rules []rewriteRule
+}
+
+type rewriteRule struct {
+	match   *regexp.Regexp
+	replace string
+	strip   []string
+}
+
+// Rewriter compiles the rules. Rules that do not compile are left out, they
+// are reported when the config is validated.
+func (c RewriteConfig) Rewriter() *Rewriter {
+	var rules []RewriteRule
+	for _, name := range c.Builtin {
+		rules = append(rules, BuiltinRewrites[name]...)
+	}
+	rules = append(rules, c.Rules...)
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	r := &Rewriter{}
+	for _, rule := range rules {
+		compiled := rewriteRule{replace: rule.Replace, strip: rule.Strip}
+		if rule.Match != "" {
+			re, err := regexp.Compile(rule.Match)
+			if err != nil {
+				continue
+			}
+			compiled.match = re
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r
+}
+
+// Apply returns the URL with every rule applied.
+func (r *Rewriter) Apply(raw string) string {
+	if r == nil || raw == "" {
+		return raw
+	}
+
+	for _, rule := range r.rules {
+		if rule.match != nil {
+			if !rule.match.MatchString(raw) {
+				continue
+			}
+			if rule.replace != "" {
+				raw = rule.match.ReplaceAllString(raw, rule.replace)
+			}
+		}
+		if len(rule.strip) > 0 {
+			raw = stripParams(raw, rule.strip)
+		}
+	}
+	return raw
+}
+
+// Item rewrites the links and enclosure URLs of the item.
+func (r *Rewriter) Item(item *gofeed.Item) {
+	if r == nil {
+		return
+	}
+
+	item.Link = r.Apply(item.Link)
+	for i := range item.Links {
+		item.Links[i] = r.Apply(item.Links[i])
+	}
+	for _, enc := range item.Enclosures {
+		enc.URL = r.Apply(enc.URL)
+	}
+}
+
+// stripParams removes the query parameters matching the names. The URL is
+// only encoded again when a parameter was removed.
+func stripParams(raw string, names []string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+
+	query := u.Query()
+	removed := false
+	for param := range query {
+		if paramMatches(param, names) {
+			query.Del(param)
+			removed = true
+		}
+	}
+
+	if !removed {
+		return raw
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func paramMatches(param string, names []string) bool {
+	for _, name := range names {
+		if prefix, ok := strings.CutSuffix(name, "*"); ok {
+			if strings.HasPrefix(param, prefix) {
+				return true
+			}
+		} else if param == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRewrite lists what is wrong with the rewrite config.
+func validateRewrite(c RewriteConfig) []string {
+	var problems []string
+
+	for _, name := range c.Builtin {
+		if _, ok := BuiltinRewrites[name]; !ok {
+			names := slices.Sorted(maps.Keys(BuiltinRewrites))
+			problems = append(problems, fmt.Sprintf("rewrite.builtin: unknown rule %q, use one of %s", name, strings.Join(names, ", ")))
+		}
+	}
+
+	for i, rule := range c.Rules {
+		if rule.Match == "" && rule.Replace != "" {
+			problems = append(problems, fmt.Sprintf("rewrite.rules[%d].replace needs match", i))
+		}
+		if rule.Replace == "" && len(rule.Strip) == 0 {
+			problems = append(problems, fmt.Sprintf("rewrite.rules[%d] has neither replace nor strip", i))
+		}
+		if _, err := regexp.Compile(rule.Match); err != nil {
+			problems = append(problems, fmt.Sprintf("rewrite.rules[%d].match is not a valid regular expression: %v", i, err))
+		}
+	}
+
+	return problems
+}
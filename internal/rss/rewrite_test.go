@@ -0,0 +1,150 @@
+package rss
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestRewrite(t *testing.T) {
+	builtins := []struct {
+		rule string
+		in   string
+		want string
+	}{
+		{"old_reddit", "https://www.reddit.com/r/golang/comments/1", "https://old.reddit.com/r/golang/comments/1"},
+		{"old_reddit", "https://reddit.com/r/golang", "https://old.reddit.com/r/golang"},
+		{"old_reddit", "http://new.reddit.com/r/golang", "https://old.reddit.com/r/golang"},
+		{"old_reddit", "https://old.reddit.com/r/golang", "https://old.reddit.com/r/golang"},
+		{"old_reddit", "https://notreddit.com/r/golang", "https://notreddit.com/r/golang"},
+		{"xcancel", "https://x.com/golang/status/1", "https://xcancel.com/golang/status/1"},
+		{"xcancel", "https://twitter.com/golang", "https://xcancel.com/golang"},
+		{"xcancel", "https://mobile.twitter.com/golang", "https://xcancel.com/golang"},
+		{"xcancel", "https://box.com/golang", "https://box.com/golang"},
+		{"strip_tracking", "https://example.com/post?utm_source=rss&utm_medium=feed&id=1", "https://example.com/post?id=1"},
+		{"strip_tracking", "https://example.com/post?fbclid=abc&gclid=def&ref=hn", "https://example.com/post"},
+		{"strip_tracking", "https://example.com/post?b=2&a=1#top", "https://example.com/post?b=2&a=1#top"},
+		{"strip_tracking", "https://example.com/post?referrer=1", "https://example.com/post?referrer=1"},
+	}
+
+	for _, tt := range builtins {
+		t.Run(tt.rule+" "+tt.in, func(t *testing.T) {
+			r := RewriteConfig{Builtin: []string{tt.rule}}.Rewriter()
+			if got := r.Apply(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Should leave URLs without rules", func(t *testing.T) {
+		r := RewriteConfig{}.Rewriter()
+		if r != nil {
+			t.Fatal("Should not make a rewriter without rules")
+		}
+		if got := r.Apply("https://reddit.com/r/golang"); got != "https://reddit.com/r/golang" {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("Should apply custom rules after built in ones", func(t *testing.T) {
+		r := RewriteConfig{
+			Builtin: []string{"old_reddit"},
+			Rules: []RewriteRule{
+				{Match: `^https://old\.reddit\.com/r/(\w+)/`, Replace: "https://reader.example.com/$1/"},
+				{Match: `^https://medium\.com/`, Strip: []string{"source"}},
+			},
+		}.Rewriter()
+
+		tests := map[string]string{
+			"https://www.reddit.com/r/golang/comments/1": "https://reader.example.com/golang/comments/1",
+			"https://medium.com/post?source=rss&id=1":    "https://medium.com/post?id=1",
+			"https://example.com/post?source=rss":        "https://example.com/post?source=rss",
+		}
+		for in, want := range tests {
+			if got := r.Apply(in); got != want {
+				t.Errorf("%q: got %q, want %q", in, got, want)
+			}
+		}
+	})
+
+	t.Run("Should rewrite item links and enclosures", func(t *testing.T) {
+		r := RewriteConfig{Builtin: []string{"strip_tracking"}}.Rewriter()
+		item := &gofeed.Item{
+			Link:       "https://example.com/1?utm_source=rss",
+			Links:      []string{"https://example.com/1?utm_source=rss"},
+			Enclosures: []*gofeed.Enclosure{{URL: "https://example.com/1.mp3?ref=feed"}},
+		}
+
+		r.Item(item)
+
+		if item.Link != "https://example.com/1" || item.Links[0] != "https://example.com/1" {
+			t.Errorf("Links not rewritten, got %q and %q", item.Link, item.Links)
+		}
+		if item.Enclosures[0].URL != "https://example.com/1.mp3" {
+			t.Errorf("Enclosure not rewritten, got %q", item.Enclosures[0].URL)
+		}
+	})
+
+	t.Run("Should rewrite links before opening", func(t *testing.T) {
+		c := DefaultConfig()
+		c.Browser = "firefox"
+		c.Rewrite.Builtin = []string{"old_reddit"}
+
+		cmd, _, err := c.OpenCommand(Link{Url: "https://www.reddit.com/r/golang"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		want := []string{"firefox", "https://old.reddit.com/r/golang"}
+		if !slices.Equal(cmd.Args, want) {
+			t.Errorf("got %q, want %q", cmd.Args, want)
+		}
+	})
+
+	t.Run("Should not duplicate items stored before rewriting", func(t *testing.T) {
+		f := &RssFeed{}
+		f.mergeItems([]*gofeed.Item{{Title: "Post", Link: "https://example.com/1?utm_source=rss"}}, nil)
+
+		r := RewriteConfig{Builtin: []string{"strip_tracking"}}.Rewriter()
+		f.mergeItems([]*gofeed.Item{
+			{Title: "Post", Link: "https://example.com/1?utm_source=rss"},
+			{Title: "New", Link: "https://example.com/2?utm_source=rss"},
+		}, r)
+
+		if len(f.RssItems) != 2 {
+			t.Fatalf("Expected 2 items, got %d", len(f.RssItems))
+		}
+		if link := f.RssItems[1].Item.Link; link != "https://example.com/2" {
+			t.Errorf("New item link not rewritten, got %q", link)
+		}
+	})
+
+	t.Run("Should report invalid rewrite rules", func(t *testing.T) {
+		c := DefaultConfig()
+		c.Rewrite = RewriteConfig{
+			Builtin: []string{"nitter"},
+			Rules: []RewriteRule{
+				{Match: "(", Replace: "x"},
+				{Replace: "https://example.com/"},
+				{Match: "example"},
+			},
+		}
+
+		err := c.Validate()
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("Expected ErrInvalidConfig, got %v", err)
+		}
+		for _, want := range []string{
+			`unknown rule "nitter", use one of old_reddit, strip_tracking, xcancel`,
+			"rewrite.rules[0].match is not a valid regular expression",
+			"rewrite.rules[1].replace needs match",
+			"rewrite.rules[2] has neither replace nor strip",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected %q in %q", want, err)
+			}
+		}
+	})
+}
@@ -123,6 +123,24 @@ func Server(t *testing.T, data []byte) *httptest.Server {
 	return server
 }
 
+func ServerConditional(t *testing.T, data []byte, etag, lastModified string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inm := r.Header.Get("If-None-Match")
+		ims := r.Header.Get("If-Modified-Since")
+		if (inm != "" && inm == etag) || (ims != "" && ims == lastModified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}))
+	return server
+}
+
 func ServerNotFound(t *testing.T) *httptest.Server {
 	t.Helper()
 
@@ -0,0 +1,186 @@
+package rss
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+	"github.com/mmcdole/gofeed/rss"
+)
+
+// Keys used to carry RSS channel hints in gofeed.Feed.Custom, which the
+// default translator drops.
+const (
+	customTTL       = "ttl"
+	customSkipHours = "skipHours"
+	customSkipDays  = "skipDays"
+)
+
+// Feeds asking to be polled less often than this are still checked weekly.
+const maxRefreshInterval = 7 * 24 * time.Hour
+
+// scheduleTranslator keeps <ttl>, <skipHours> and <skipDays> of RSS feeds.
+type scheduleTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+func (t *scheduleTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	result, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := feed.(*rss.Feed)
+	if !ok {
+		return result, nil
+	}
+
+	custom := map[string]string{
+		customTTL:       strings.TrimSpace(raw.TTL),
+		customSkipHours: strings.Join(raw.SkipHours, ","),
+		customSkipDays:  strings.Join(raw.SkipDays, ","),
+	}
+	for k, v := range custom {
+		if v == "" {
+			continue
+		}
+		if result.Custom == nil {
+			result.Custom = make(map[string]string)
+		}
+		result.Custom[k] = v
+	}
+
+	return result, nil
+}
+
+func newParser() *gofeed.Parser {
+	parser := gofeed.NewParser()
+	parser.RSSTranslator = &scheduleTranslator{}
+	return parser
+}
+
+// due reports whether the feed asked not to be polled before now
+func (f *RssFeed) due(now time.Time) bool {
+	return !now.Before(f.NextRefresh)
+}
+
+// nextRefresh returns the earliest time the feed wants to be polled again,
+// based on <ttl>, the Syndication module and <skipHours>/<skipDays>. Feeds
+// without hints are due right away.
+func nextRefresh(feed *gofeed.Feed, now time.Time) time.Time {
+	if feed == nil {
+		return now
+	}
+
+	next := now.Add(min(refreshInterval(feed), maxRefreshInterval))
+
+	skipHours := skipHours(feed)
+	skipDays := skipDays(feed)
+	if len(skipHours) == 0 && len(skipDays) == 0 {
+		return next
+	}
+
+	// Skip hints are in GMT. Step forward an hour at a time, at most a week,
+	// so a feed skipping every hour is still polled eventually.
+	t := next.UTC()
+	for range 7 * 24 {
+		if !slices.Contains(skipHours, t.Hour()) && !slices.Contains(skipDays, t.Weekday()) {
+			break
+		}
+		t = t.Truncate(time.Hour).Add(time.Hour)
+	}
+	return t.In(next.Location())
+}
+
+func refreshInterval(feed *gofeed.Feed) time.Duration {
+	var interval time.Duration
+
+	if minutes, err := strconv.Atoi(feed.Custom[customTTL]); err == nil && minutes > 0 {
+		interval = time.Duration(minutes) * time.Minute
+	}
+
+	return max(interval, syndicationInterval(feed))
+}
+
+// syndicationInterval reads sy:updatePeriod and sy:updateFrequency, the
+// period being split into frequency updates.
+func syndicationInterval(feed *gofeed.Feed) time.Duration {
+	sy, ok := feed.Extensions["sy"]
+	if !ok {
+		return 0
+	}
+
+	var period time.Duration
+	switch strings.ToLower(extensionValue(sy, "updatePeriod")) {
+	case "hourly":
+		period = time.Hour
+	case "daily":
+		period = 24 * time.Hour
+	case "weekly":
+		period = 7 * 24 * time.Hour
+	case "monthly":
+		period = 30 * 24 * time.Hour
+	case "yearly":
+		period = 365 * 24 * time.Hour
+	default:
+		return 0
+	}
+
+	frequency, err := strconv.Atoi(extensionValue(sy, "updateFrequency"))
+	if err != nil || frequency < 1 {
+		frequency = 1
+	}
+
+	return period / time.Duration(frequency)
+}
+
+func extensionValue(extensions map[string][]ext.Extension, name string) string {
+	values := extensions[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(values[0].Value)
+}
+
+func skipHours(feed *gofeed.Feed) []int {
+	var hours []int
+	for _, v := range splitCustom(feed, customSkipHours) {
+		hour, err := strconv.Atoi(v)
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		// Some feeds use 24 for midnight
+		hours = append(hours, hour%24)
+	}
+	return hours
+}
+
+func skipDays(feed *gofeed.Feed) []time.Weekday {
+	var days []time.Weekday
+	for _, v := range splitCustom(feed, customSkipDays) {
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(v, d.String()) {
+				days = append(days, d)
+			}
+		}
+	}
+	return days
+}
+
+func splitCustom(feed *gofeed.Feed, key string) []string {
+	value := feed.Custom[key]
+	if value == "" {
+		return nil
+	}
+
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
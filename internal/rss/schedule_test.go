@@ -0,0 +1,182 @@
+package rss
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func syndication(period, frequency string) ext.Extensions {
+	return ext.Extensions{
+		"sy": {
+			"updatePeriod":    {{Value: period}},
+			"updateFrequency": {{Value: frequency}},
+		},
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	t.Run("Should keep RSS schedule hints when parsing", func(t *testing.T) {
+		server := Server(t, testData(t, "feed_schedule.xml"))
+		defer server.Close()
+
+		rssFeed := RssFeed{Url: server.URL}
+
+		err := rssFeed.GetFeed()
+		if err != nil {
+			t.Fatalf("Error getting feed %q", err)
+		}
+
+		custom := rssFeed.Feed.Custom
+		if custom[customTTL] != "60" {
+			t.Errorf("TTL not kept, got %q", custom[customTTL])
+		}
+		if custom[customSkipHours] != "0,1" {
+			t.Errorf("Skip hours not kept, got %q", custom[customSkipHours])
+		}
+		if custom[customSkipDays] != "Sunday" {
+			t.Errorf("Skip days not kept, got %q", custom[customSkipDays])
+		}
+
+		if !rssFeed.NextRefresh.After(time.Now().Add(5 * time.Hour)) {
+			t.Errorf("Next refresh should honor updatePeriod, got %s", rssFeed.NextRefresh)
+		}
+	})
+
+	t.Run("Should compute next refresh", func(t *testing.T) {
+		// Wednesday
+		now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		tests := []struct {
+			name     string
+			feed     *gofeed.Feed
+			expected time.Time
+		}{
+			{
+				name:     "no hints",
+				feed:     &gofeed.Feed{},
+				expected: now,
+			},
+			{
+				name:     "unloaded feed",
+				feed:     nil,
+				expected: now,
+			},
+			{
+				name:     "ttl",
+				feed:     &gofeed.Feed{Custom: map[string]string{customTTL: "30"}},
+				expected: now.Add(30 * time.Minute),
+			},
+			{
+				name:     "invalid ttl",
+				feed:     &gofeed.Feed{Custom: map[string]string{customTTL: "soon"}},
+				expected: now,
+			},
+			{
+				name:     "update period",
+				feed:     &gofeed.Feed{Extensions: syndication("hourly", "")},
+				expected: now.Add(time.Hour),
+			},
+			{
+				name:     "update period and frequency",
+				feed:     &gofeed.Feed{Extensions: syndication("daily", "2")},
+				expected: now.Add(12 * time.Hour),
+			},
+			{
+				name: "longest of ttl and update period",
+				feed: &gofeed.Feed{
+					Custom:     map[string]string{customTTL: "180"},
+					Extensions: syndication("hourly", "1"),
+				},
+				expected: now.Add(3 * time.Hour),
+			},
+			{
+				name:     "capped interval",
+				feed:     &gofeed.Feed{Extensions: syndication("yearly", "1")},
+				expected: now.Add(maxRefreshInterval),
+			},
+			{
+				name: "skip hours",
+				feed: &gofeed.Feed{Custom: map[string]string{
+					customTTL:       "60",
+					customSkipHours: "13,14,24",
+				}},
+				expected: time.Date(2025, 1, 1, 15, 0, 0, 0, time.UTC),
+			},
+			{
+				name: "skip days",
+				feed: &gofeed.Feed{Custom: map[string]string{
+					customSkipDays: "Wednesday,thursday",
+				}},
+				expected: time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				name: "skip every day",
+				feed: &gofeed.Feed{Custom: map[string]string{
+					customSkipDays: "Monday,Tuesday,Wednesday,Thursday,Friday,Saturday,Sunday",
+				}},
+				expected: now.Add(maxRefreshInterval),
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got := nextRefresh(tt.feed, now)
+				if !got.Equal(tt.expected) {
+					t.Errorf("nextRefresh() = %s, want %s", got, tt.expected)
+				}
+			})
+		}
+	})
+
+	t.Run("Should skip feeds that are not due", func(t *testing.T) {
+		server := Server(t, testData(t, "feed.xml"))
+		defer server.Close()
+
+		rssFeed := &RssFeed{Url: server.URL, NextRefresh: time.Now().Add(time.Hour)}
+
+		results, err := UpdateFeeds(rssFeed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		res := <-results
+		if !res.Skipped || res.Err != nil {
+			t.Error("Feed that is not due should be skipped")
+		}
+
+		if rssFeed.Feed != nil {
+			t.Error("Feed that is not due should not be fetched")
+		}
+	})
+
+	t.Run("Should force refresh of feeds that are not due", func(t *testing.T) {
+		server := Server(t, testData(t, "feed.xml"))
+		defer server.Close()
+
+		rssFeed := &RssFeed{
+			Url:         server.URL,
+			NextRefresh: time.Now().Add(time.Hour),
+			RetryAt:     time.Now().Add(time.Hour),
+		}
+
+		opts := DefaultUpdateOptions
+		opts.Force = true
+
+		results, err := UpdateFeedsWithOptions(opts, rssFeed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		res := <-results
+		if res.Skipped || res.Err != nil {
+			t.Errorf("Forced feed should be fetched, got %v", res.Err)
+		}
+
+		if rssFeed.Feed == nil {
+			t.Error("Forced feed not fetched")
+		}
+	})
+}
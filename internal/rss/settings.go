@@ -0,0 +1,91 @@
+package rss
+
+import (
+	"strings"
+	"time"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// FeedSettings is a single entry in urls.yaml. An entry is either a plain
+// URL or an object with the URL and optional per-feed settings:
+//
+//	golang:
+//	  - https://go.dev/blog/feed.atom
+//	  - url: https://www.reddit.com/r/golang.rss
+//	    title: r/golang
+//	    interval: 30m
+//	    headers:
+//	      User-Agent: rssr
+//	    tags: [reddit]
+//	    filters: [hiring]
+type FeedSettings struct {
+	Url   string `yaml:"url"`
+	Title string `yaml:"title"`
+	// Interval overrides the auto refresh interval from config.yaml
+	Interval time.Duration `yaml:"interval"`
+	// Headers are sent with every request for the feed
+	Headers map[string]string `yaml:"headers"`
+	Tags    []string          `yaml:"tags"`
+	// Filters hide new items whose title contains any of the words
+	Filters []string `yaml:"filters"`
+}
+
+func (s *FeedSettings) UnmarshalYAML(data []byte) error {
+	var u string
+	if err := yaml.Unmarshal(data, &u); err == nil {
+		*s = FeedSettings{Url: u}
+		return nil
+	}
+
+	type plain FeedSettings
+	var p plain
+	if err := yaml.UnmarshalWithOptions(data, &p, yaml.Strict()); err != nil {
+		return err
+	}
+
+	if p.Url == "" {
+		return ErrFeedHasNoUrl
+	}
+
+	*s = FeedSettings(p)
+	return nil
+}
+
+// MarshalYAML writes the short plain URL form when there is nothing else
+// to the entry.
+func (s FeedSettings) MarshalYAML() (interface{}, error) {
+	if s.Title == "" && s.Interval == 0 && len(s.Headers) == 0 &&
+		len(s.Tags) == 0 && len(s.Filters) == 0 {
+		return s.Url, nil
+	}
+
+	entry := yaml.MapSlice{{Key: "url", Value: s.Url}}
+	if s.Title != "" {
+		entry = append(entry, yaml.MapItem{Key: "title", Value: s.Title})
+	}
+	if s.Interval != 0 {
+		entry = append(entry, yaml.MapItem{Key: "interval", Value: s.Interval.String()})
+	}
+	if len(s.Headers) > 0 {
+		entry = append(entry, yaml.MapItem{Key: "headers", Value: s.Headers})
+	}
+	if len(s.Tags) > 0 {
+		entry = append(entry, yaml.MapItem{Key: "tags", Value: s.Tags})
+	}
+	if len(s.Filters) > 0 {
+		entry = append(entry, yaml.MapItem{Key: "filters", Value: s.Filters})
+	}
+	return entry, nil
+}
+
+// filtered reports whether a new item should be left out of the feed
+func (s FeedSettings) filtered(title string) bool {
+	title = strings.ToLower(title)
+	for _, f := range s.Filters {
+		if f != "" && strings.Contains(title, strings.ToLower(f)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package rss
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestSettings(t *testing.T) {
+	t.Run("Should read plain and object entries", func(t *testing.T) {
+		l := NewListWithDefaults()
+		fs := fstest.MapFS{
+			"urls.yaml": {Data: []byte(`golang:
+  - https://go.dev/blog/feed.atom
+  - url: https://www.reddit.com/r/golang.rss
+    title: r/golang
+    interval: 30m
+    headers:
+      Cookie: over18=1
+    tags: [reddit]
+    filters: [hiring]
+`)},
+		}
+
+		if err := l.CreateFeedsFromYaml(fs, "urls.yaml"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if len(l.CategoryIndex["golang"]) != 2 {
+			t.Fatalf("Wrong number of feeds, got %d", len(l.CategoryIndex["golang"]))
+		}
+
+		plain := l.FeedIndex["https://go.dev/blog/feed.atom"]
+		if plain == nil || plain.Category != "golang" {
+			t.Fatal("Plain entry not created")
+		}
+
+		feed := l.FeedIndex["https://www.reddit.com/r/golang.rss"]
+		if feed == nil {
+			t.Fatal("Object entry not created")
+		}
+
+		s := feed.Settings
+		if s.Title != "r/golang" || s.Interval != 30*time.Minute ||
+			s.Headers["Cookie"] != "over18=1" || len(s.Tags) != 1 || len(s.Filters) != 1 {
+			t.Errorf("Settings not read: %+v", s)
+		}
+
+		if feed.Title() != "r/golang" {
+			t.Errorf("Title override not used, got %q", feed.Title())
+		}
+	})
+
+	t.Run("Should reject unknown settings", func(t *testing.T) {
+		l := NewListWithDefaults()
+		fs := fstest.MapFS{
+			"urls.yaml": {Data: []byte("golang:\n  - url: https://go.dev/blog/feed.atom\n    titel: Go\n")},
+		}
+
+		if err := l.CreateFeedsFromYaml(fs, "urls.yaml"); err == nil {
+			t.Error("Should report misspelled setting")
+		}
+	})
+
+	t.Run("Should require url", func(t *testing.T) {
+		l := NewListWithDefaults()
+		fs := fstest.MapFS{
+			"urls.yaml": {Data: []byte("golang:\n  - title: Go\n")},
+		}
+
+		if err := l.CreateFeedsFromYaml(fs, "urls.yaml"); err == nil {
+			t.Error("Should report entry without url")
+		}
+	})
+
+	t.Run("Should send custom headers", func(t *testing.T) {
+		var cookie, agent string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie = r.Header.Get("Cookie")
+			agent = r.Header.Get("User-Agent")
+			w.Write(testData(t, "feed.xml"))
+		}))
+		defer server.Close()
+
+		feed := &RssFeed{
+			Url: server.URL,
+			Settings: FeedSettings{
+				Headers: map[string]string{"Cookie": "over18=1", "User-Agent": "custom"},
+			},
+		}
+
+		if err := feed.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if cookie != "over18=1" || agent != "custom" {
+			t.Errorf("Headers not sent, got cookie %q agent %q", cookie, agent)
+		}
+	})
+
+	t.Run("Should filter items by title", func(t *testing.T) {
+		server := Server(t, testData(t, "feed.xml"))
+		defer server.Close()
+
+		feed := &RssFeed{
+			Url:      server.URL,
+			Settings: FeedSettings{Filters: []string{"roscosmos"}},
+		}
+
+		if err := feed.GetFeed(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		for _, item := range feed.RssItems {
+			if item.Item.Title == "NASA Plans Coverage of Roscosmos Spacewalk Outside Space Station" {
+				t.Error("Filtered item kept")
+			}
+		}
+		if len(feed.RssItems) == 0 {
+			t.Error("All items filtered")
+		}
+	})
+
+	t.Run("Should prefer feed interval", func(t *testing.T) {
+		r := RefreshConfig{
+			Interval: time.Hour,
+			Feeds:    map[string]time.Duration{"example.com": 2 * time.Hour},
+		}
+		feed := &RssFeed{Url: "example.com", Settings: FeedSettings{Interval: 10 * time.Minute}}
+
+		if got := r.IntervalFor(feed); got != 10*time.Minute {
+			t.Errorf("got %v, want 10m", got)
+		}
+
+		if !(RefreshConfig{}).Enabled(feed) {
+			t.Error("Interval in urls.yaml should enable auto refresh")
+		}
+	})
+}
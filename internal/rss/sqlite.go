@@ -0,0 +1,571 @@
+package rss
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS feeds (
+	url           TEXT PRIMARY KEY,
+	feed          TEXT,
+	error         TEXT NOT NULL DEFAULT '',
+	etag          TEXT NOT NULL DEFAULT '',
+	last_modified TEXT NOT NULL DEFAULT '',
+	failures      INTEGER NOT NULL DEFAULT 0,
+	retry_at      INTEGER NOT NULL DEFAULT 0,
+	next_refresh  INTEGER NOT NULL DEFAULT 0,
+	last_refresh  INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS items (
+	feed_url   TEXT NOT NULL,
+	guid       TEXT NOT NULL,
+	item       TEXT NOT NULL,
+	feed_title TEXT NOT NULL DEFAULT '',
+	search     TEXT NOT NULL DEFAULT '',
+	ts         INTEGER NOT NULL DEFAULT 0,
+	read       INTEGER NOT NULL DEFAULT 0,
+	bookmark   INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (feed_url, guid)
+);
+
+CREATE TABLE IF NOT EXISTS pruned (
+	feed_url TEXT NOT NULL,
+	guid     TEXT NOT NULL,
+	PRIMARY KEY (feed_url, guid)
+);
+
+CREATE INDEX IF NOT EXISTS items_unread ON items (feed_url) WHERE read = 0;
+CREATE INDEX IF NOT EXISTS items_ts ON items (ts);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS items_fts USING fts5 (
+	search, content = 'items', content_rowid = 'rowid'
+);
+
+CREATE TRIGGER IF NOT EXISTS items_fts_insert AFTER INSERT ON items BEGIN
+	INSERT INTO items_fts (rowid, search) VALUES (new.rowid, new.search);
+END;
+
+CREATE TRIGGER IF NOT EXISTS items_fts_delete AFTER DELETE ON items BEGIN
+	INSERT INTO items_fts (items_fts, rowid, search) VALUES ('delete', old.rowid, old.search);
+END;
+`
+
+// SQLiteStorage keeps the list in an SQLite database. Saves only write the
+// feeds that were refreshed, the items that are new and the state that
+// changed since the last load or save.
+type SQLiteStorage struct {
+	db *sql.DB
+	// Ts of every stored item and last refresh of every stored feed, as
+	// this instance last read or wrote them
+	items map[itemKey]int64
+	feeds map[string]int64
+	// Keys of pruned items as this instance last read or wrote them
+	pruned map[itemKey]bool
+	// Highest item Ts seen, later ones were saved by another instance
+	seen int64
+}
+
+type itemKey struct {
+	feed, guid string
+}
+
+// OpenSQLiteStorage opens the database at path, creating it when needed.
+// Other instances wait for each other through SQLite locking.
+func OpenSQLiteStorage(path string) (*SQLiteStorage, error) {
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() +
+		"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStorage{
+		db:     db,
+		items:  make(map[itemKey]int64),
+		feeds:  make(map[string]int64),
+		pruned: make(map[itemKey]bool),
+	}, nil
+}
+
+// MigrateJSON copies the feeds and items of data.json at path into the
+// database the first time it is called on the database, and reports
+// whether it did. data.json is left as it is.
+func (s *SQLiteStorage) MigrateJSON(path string) (bool, error) {
+	var done int
+	err := s.db.QueryRow("SELECT count(*) FROM meta WHERE key = 'migrated_json'").Scan(&done)
+	if err != nil || done > 0 {
+		return false, err
+	}
+
+	var saved List
+	migrated := false
+
+	f, err := os.Open(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if err == nil {
+		defer f.Close()
+		if err := json.NewDecoder(f).Decode(&saved); err != nil {
+			return false, err
+		}
+		migrated = true
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := s.write(tx, saved.Feeds); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("INSERT INTO meta (key, value) VALUES ('migrated_json', ?)", path); err != nil {
+		return false, err
+	}
+
+	return migrated, tx.Commit()
+}
+
+func (s *SQLiteStorage) Load(l *List) error {
+	rows, err := s.db.Query(`SELECT url, feed, error, etag, last_modified, failures,
+		retry_at, next_refresh, last_refresh FROM feeds`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stored RssFeed
+		var feedJSON sql.NullString
+		var retryAt, nextRefresh, lastRefresh int64
+		err := rows.Scan(&stored.Url, &feedJSON, &stored.Error, &stored.ETag, &stored.LastModified,
+			&stored.Failures, &retryAt, &nextRefresh, &lastRefresh)
+		if err != nil {
+			return err
+		}
+		s.feeds[stored.Url] = lastRefresh
+
+		feed := l.FeedIndex[stored.Url]
+		if feed == nil || feed.Url == "Bookmarks" {
+			continue
+		}
+
+		feed.Error = stored.Error
+		feed.ETag = stored.ETag
+		feed.LastModified = stored.LastModified
+		feed.Failures = stored.Failures
+		feed.RetryAt = fromUnixNano(retryAt)
+		feed.NextRefresh = fromUnixNano(nextRefresh)
+		feed.LastRefresh = fromUnixNano(lastRefresh)
+		feed.Feed = nil
+		feed.RssItems = nil
+		feed.Pruned = nil
+		if feedJSON.Valid {
+			feed.Feed = &gofeed.Feed{}
+			if err := json.Unmarshal([]byte(feedJSON.String), feed.Feed); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	items, err := s.db.Query(`SELECT feed_url, guid, item, feed_title, ts, read, bookmark
+		FROM items ORDER BY rowid`)
+	if err != nil {
+		return err
+	}
+	defer items.Close()
+
+	for items.Next() {
+		var key itemKey
+		var itemJSON string
+		item := &RssItem{Item: &gofeed.Item{}}
+		err := items.Scan(&key.feed, &key.guid, &itemJSON, &item.FeedTitle, &item.Ts, &item.Read, &item.Bookmark)
+		if err != nil {
+			return err
+		}
+		s.items[key] = item.Ts
+		s.seen = max(s.seen, item.Ts)
+
+		feed := l.FeedIndex[key.feed]
+		if feed == nil || feed.Url == "Bookmarks" {
+			continue
+		}
+
+		if err := json.Unmarshal([]byte(itemJSON), item.Item); err != nil {
+			return err
+		}
+		feed.RssItems = append(feed.RssItems, item)
+		l.ItemIndex[item.GUID()] = item
+		if item.Bookmark {
+			l.Bookmarks().RssItems = append(l.Bookmarks().RssItems, item)
+		}
+	}
+
+	if err := items.Err(); err != nil {
+		return err
+	}
+
+	// Items fetched later are stored after older ones
+	for _, feed := range l.Feeds {
+		if feed.Url != "Bookmarks" {
+			feed.SortByDate()
+		}
+	}
+
+	pruned, err := s.readPruned(s.db, false)
+	if err != nil {
+		return err
+	}
+	for key := range pruned {
+		s.pruned[key] = true
+		if feed := l.FeedIndex[key.feed]; feed != nil && feed.Url != "Bookmarks" {
+			feed.forget(key.guid)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) Save(l *List) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = s.write(tx, l.Feeds)
+	if err == nil {
+		err = s.merge(tx, l)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		// What was written is unknown, the next save writes everything
+		clear(s.items)
+		clear(s.feeds)
+		clear(s.pruned)
+	}
+	return err
+}
+
+// write stores the feeds that were refreshed, the items that are new and
+// the state that changed, and deletes the items that were pruned. Stored
+// state that changed later is kept.
+func (s *SQLiteStorage) write(tx *sql.Tx, feeds []*RssFeed) error {
+	pruned := make(map[itemKey]bool)
+	for _, feed := range feeds {
+		if feed.Url == "Bookmarks" {
+			continue
+		}
+
+		if last, ok := s.feeds[feed.Url]; !ok || last != unixNano(feed.LastRefresh) {
+			if err := writeFeed(tx, feed); err != nil {
+				return err
+			}
+			s.feeds[feed.Url] = unixNano(feed.LastRefresh)
+		}
+
+		for _, item := range feed.RssItems {
+			if item.Item == nil {
+				continue
+			}
+
+			key := itemKey{feed.Url, item.GUID()}
+			ts, ok := s.items[key]
+			switch {
+			case !ok:
+				if err := insertItem(tx, key, item); err != nil {
+					return err
+				}
+			case item.Ts > ts && item.Bookmark:
+				// Brings the item back when another instance pruned it
+				if err := insertItem(tx, key, item); err != nil {
+					return err
+				}
+			case item.Ts > ts:
+				_, err := tx.Exec(`UPDATE items SET ts = ?, read = ?, bookmark = ?
+					WHERE feed_url = ? AND guid = ? AND ts < ?`,
+					item.Ts, item.Read, item.Bookmark, key.feed, key.guid, item.Ts)
+				if err != nil {
+					return err
+				}
+			default:
+				continue
+			}
+			s.items[key] = item.Ts
+		}
+
+		for guid := range feed.Pruned {
+			pruned[itemKey{feed.Url, guid}] = true
+		}
+	}
+
+	return s.writePruned(tx, feeds, pruned)
+}
+
+// writePruned deletes the items that were pruned since the last save, unless
+// another instance bookmarked them, and forgets the keys of pruned items
+// that are gone from their feed.
+func (s *SQLiteStorage) writePruned(tx *sql.Tx, feeds []*RssFeed, pruned map[itemKey]bool) error {
+	for key := range pruned {
+		if s.pruned[key] {
+			continue
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO pruned (feed_url, guid) VALUES (?, ?)", key.feed, key.guid); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM items WHERE feed_url = ? AND guid = ? AND bookmark = 0", key.feed, key.guid); err != nil {
+			return err
+		}
+		s.pruned[key] = true
+		delete(s.items, key)
+	}
+
+	saved := make(map[string]bool, len(feeds))
+	for _, feed := range feeds {
+		saved[feed.Url] = true
+	}
+	for key := range s.pruned {
+		if pruned[key] || !saved[key.feed] {
+			continue
+		}
+		if _, err := tx.Exec("DELETE FROM pruned WHERE feed_url = ? AND guid = ?", key.feed, key.guid); err != nil {
+			return err
+		}
+		delete(s.pruned, key)
+	}
+	return nil
+}
+
+// readPruned returns the keys of pruned items that are not bookmarked, only
+// the ones this instance does not know of when fresh is set.
+func (s *SQLiteStorage) readPruned(q querier, fresh bool) (map[itemKey]bool, error) {
+	rows, err := q.Query(`SELECT feed_url, guid FROM pruned WHERE NOT EXISTS (
+		SELECT 1 FROM items WHERE items.feed_url = pruned.feed_url
+			AND items.guid = pruned.guid AND items.bookmark = 1)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pruned := make(map[itemKey]bool)
+	for rows.Next() {
+		var key itemKey
+		if err := rows.Scan(&key.feed, &key.guid); err != nil {
+			return nil, err
+		}
+		if !fresh || !s.pruned[key] {
+			pruned[key] = true
+		}
+	}
+	return pruned, rows.Err()
+}
+
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// merge takes the state other instances saved later than this one, and
+// drops the items they pruned.
+func (s *SQLiteStorage) merge(tx *sql.Tx, l *List) error {
+	rows, err := tx.Query("SELECT feed_url, guid, ts, read, bookmark FROM items WHERE ts > ?", s.seen)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	l.ReindexList()
+	for rows.Next() {
+		var key itemKey
+		var ts int64
+		var read, bookmark bool
+		if err := rows.Scan(&key.feed, &key.guid, &ts, &read, &bookmark); err != nil {
+			return err
+		}
+		s.seen = max(s.seen, ts)
+
+		item := l.ItemIndex[key.guid]
+		if item == nil || ts <= item.Ts {
+			continue
+		}
+		item.Ts = ts
+		item.Read = read
+		if err := l.SetBookmark(bookmark, item); err != nil {
+			return err
+		}
+		s.items[key] = ts
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	pruned, err := s.readPruned(tx, true)
+	if err != nil {
+		return err
+	}
+	byFeed := make(map[string]map[string]bool)
+	for key := range pruned {
+		if byFeed[key.feed] == nil {
+			byFeed[key.feed] = make(map[string]bool)
+		}
+		byFeed[key.feed][key.guid] = true
+	}
+	for url, keys := range byFeed {
+		feed := l.FeedIndex[url]
+		if feed == nil || feed.Url == "Bookmarks" {
+			continue
+		}
+		l.dropPruned(feed, keys)
+		// Keys of items this instance does not have are left unknown, so
+		// the next save does not take them for gone
+		for guid := range keys {
+			if feed.Pruned[guid] {
+				s.pruned[itemKey{url, guid}] = true
+			}
+		}
+	}
+	return nil
+}
+
+func writeFeed(tx *sql.Tx, feed *RssFeed) error {
+	var feedJSON []byte
+	if feed.Feed != nil {
+		// Items are stored in their own table
+		meta := *feed.Feed
+		meta.Items = nil
+
+		var err error
+		if feedJSON, err = json.Marshal(meta); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(`INSERT INTO feeds (url, feed, error, etag, last_modified, failures,
+			retry_at, next_refresh, last_refresh)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (url) DO UPDATE SET feed = excluded.feed, error = excluded.error,
+			etag = excluded.etag, last_modified = excluded.last_modified,
+			failures = excluded.failures, retry_at = excluded.retry_at,
+			next_refresh = excluded.next_refresh, last_refresh = excluded.last_refresh`,
+		feed.Url, nullString(feedJSON), feed.Error, feed.ETag, feed.LastModified, feed.Failures,
+		unixNano(feed.RetryAt), unixNano(feed.NextRefresh), unixNano(feed.LastRefresh))
+	return err
+}
+
+func insertItem(tx *sql.Tx, key itemKey, item *RssItem) error {
+	itemJSON, err := json.Marshal(item.Item)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO items (feed_url, guid, item, feed_title, search, ts, read, bookmark)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (feed_url, guid) DO UPDATE SET ts = excluded.ts, read = excluded.read,
+			bookmark = excluded.bookmark WHERE excluded.ts > items.ts`,
+		key.feed, key.guid, string(itemJSON), item.FeedTitle, searchText(item),
+		item.Ts, item.Read, item.Bookmark)
+	return err
+}
+
+func (s *SQLiteStorage) UnreadCounts() (map[string]int, error) {
+	rows, err := s.db.Query("SELECT feed_url, count(*) FROM items WHERE read = 0 GROUP BY feed_url")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var feed string
+		var n int
+		if err := rows.Scan(&feed, &n); err != nil {
+			return nil, err
+		}
+		counts[feed] = n
+	}
+	return counts, rows.Err()
+}
+
+func (s *SQLiteStorage) Search(query string) ([]string, error) {
+	guids := []string{}
+	match := ftsQuery(query)
+	if match == "" {
+		return guids, nil
+	}
+
+	rows, err := s.db.Query(`SELECT items.guid FROM items_fts
+		JOIN items ON items.rowid = items_fts.rowid
+		WHERE items_fts MATCH ? ORDER BY rank`, match)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var guid string
+		if err := rows.Scan(&guid); err != nil {
+			return nil, err
+		}
+		guids = append(guids, guid)
+	}
+	return guids, rows.Err()
+}
+
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// ftsQuery matches words starting with every word of query. The words are
+// quoted so FTS5 syntax in them is searched for as text.
+func ftsQuery(query string) string {
+	words := searchWords(query)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"*`
+	}
+	return strings.Join(words, " ")
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+func nullString(b []byte) sql.NullString {
+	return sql.NullString{String: string(b), Valid: b != nil}
+}
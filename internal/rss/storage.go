@@ -0,0 +1,149 @@
+package rss
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Storage backends that can be set in config.yaml
+const (
+	StorageJSON   = "json"
+	StorageSQLite = "sqlite"
+)
+
+var StorageBackends = []string{StorageJSON, StorageSQLite}
+
+// Storage keeps the fetched feeds and items of a list, with their read and
+// bookmark state, between runs.
+type Storage interface {
+	// Load restores the feeds of l that were stored.
+	Load(l *List) error
+	// Save stores l, after merging the state other instances saved since
+	// by item Ts.
+	Save(l *List) error
+	// UnreadCounts returns the unread items of every stored feed by URL,
+	// as last saved.
+	UnreadCounts() (map[string]int, error)
+	// Search returns the GUIDs of the items with a word starting with every
+	// word of query in their title or description, as last saved.
+	Search(query string) ([]string, error)
+	Close() error
+}
+
+// OpenStorage opens the storage backend in the cache dir. The SQLite
+// database takes over data.json the first time it is opened.
+func OpenStorage(backend string) (Storage, error) {
+	dataFilePath, err := DataFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	if backend != StorageSQLite {
+		return NewJSONStorage(dataFilePath), nil
+	}
+
+	s, err := OpenSQLiteStorage(filepath.Join(filepath.Dir(dataFilePath), "rssr.db"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.MigrateJSON(dataFilePath); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// JSONStorage keeps the list in data.json, written in full on every save.
+// The queries run on the list it last loaded or saved.
+type JSONStorage struct {
+	path string
+	l    *List
+}
+
+func NewJSONStorage(path string) *JSONStorage {
+	return &JSONStorage{path: path}
+}
+
+func (s *JSONStorage) Load(l *List) error {
+	s.l = l
+	return withDataLock(s.path, func() error {
+		return loadDataFile(l, s.path)
+	})
+}
+
+func (s *JSONStorage) Save(l *List) error {
+	s.l = l
+	return withDataLock(s.path, func() error {
+		if err := mergeDataFile(l, s.path); err != nil {
+			return err
+		}
+		return saveDataFile(l, s.path, time.Now())
+	})
+}
+
+func (s *JSONStorage) UnreadCounts() (map[string]int, error) {
+	counts := make(map[string]int)
+	if s.l == nil {
+		return counts, nil
+	}
+
+	for _, feed := range s.l.Feeds {
+		if feed.Url == "Bookmarks" {
+			continue
+		}
+		for _, item := range feed.RssItems {
+			if item.Item != nil && !item.Read {
+				counts[feed.Url]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+func (s *JSONStorage) Search(query string) ([]string, error) {
+	guids := []string{}
+	words := searchWords(query)
+	if s.l == nil || len(words) == 0 {
+		return guids, nil
+	}
+
+	for _, feed := range s.l.Feeds {
+		if feed.Url == "Bookmarks" {
+			continue
+		}
+		for _, item := range feed.RssItems {
+			if item.Item == nil {
+				continue
+			}
+			text := searchWords(searchText(item))
+			if !slices.ContainsFunc(words, func(w string) bool { return !hasWordPrefix(text, w) }) {
+				guids = append(guids, item.GUID())
+			}
+		}
+	}
+	return guids, nil
+}
+
+func (s *JSONStorage) Close() error {
+	return nil
+}
+
+// searchText is what Search looks in.
+func searchText(item *RssItem) string {
+	return item.Item.Title + " " + item.Description()
+}
+
+// searchWords splits text into lower case words the way SQLite full text
+// search does.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func hasWordPrefix(words []string, prefix string) bool {
+	return slices.ContainsFunc(words, func(w string) bool { return strings.HasPrefix(w, prefix) })
+}
@@ -0,0 +1,303 @@
+package rss
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestStorage(t *testing.T) {
+	const feedUrl = "https://example.com/feed"
+
+	newStorageList := func() *List {
+		l := NewListWithDefaults()
+		l.AddFeed("golang", FeedSettings{Url: feedUrl})
+		return l
+	}
+
+	fetched := func(l *List) *RssFeed {
+		feed := l.FeedIndex[feedUrl]
+		feed.Feed = &gofeed.Feed{Title: "Example"}
+		feed.ETag = `"v1"`
+		feed.LastRefresh = time.Unix(100, 0)
+		feed.RssItems = []*RssItem{
+			{Item: &gofeed.Item{GUID: "1", Title: "Go generics", Description: "Type parameters in practice"}},
+			{Item: &gofeed.Item{GUID: "2", Title: "Rust lifetimes", Description: "Borrowing, explained"}},
+			{Item: &gofeed.Item{GUID: "3", Title: "Zig comptime", Description: "Go-like simplicity"}},
+		}
+		return feed
+	}
+
+	backends := map[string]func(t *testing.T, dir string) Storage{
+		StorageJSON: func(t *testing.T, dir string) Storage {
+			return NewJSONStorage(filepath.Join(dir, "data.json"))
+		},
+		StorageSQLite: func(t *testing.T, dir string) Storage {
+			s, err := OpenSQLiteStorage(filepath.Join(dir, "rssr.db"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+			t.Cleanup(func() { s.Close() })
+			return s
+		},
+	}
+
+	for name, open := range backends {
+		t.Run(name+" should save and load feeds, items and state", func(t *testing.T) {
+			dir := t.TempDir()
+
+			l := newStorageList()
+			fetched(l).RssItems[1].ToggleBookmark()
+			l.SetBookmark(true, l.FeedIndex[feedUrl].RssItems[1])
+			l.FeedIndex[feedUrl].RssItems[0].MarkRead()
+			if err := open(t, dir).Save(l); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			loaded := newStorageList()
+			if err := open(t, dir).Load(loaded); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			feed := loaded.FeedIndex[feedUrl]
+			if feed.Feed == nil || feed.Feed.Title != "Example" || feed.ETag != `"v1"` || !feed.LastRefresh.Equal(time.Unix(100, 0)) {
+				t.Errorf("Feed not restored: %+v", feed)
+			}
+			if len(feed.RssItems) != 3 || feed.RssItems[2].Item.Title != "Zig comptime" {
+				t.Fatalf("Items not restored in order: %+v", feed.RssItems)
+			}
+			if !feed.RssItems[0].Read || feed.RssItems[1].Read {
+				t.Error("Read state not restored")
+			}
+			if bookmarks := loaded.Bookmarks().RssItems; len(bookmarks) != 1 || bookmarks[0] != feed.RssItems[1] {
+				t.Errorf("Bookmarks not restored: %+v", bookmarks)
+			}
+		})
+
+		t.Run(name+" should count unread items and search", func(t *testing.T) {
+			s := open(t, t.TempDir())
+
+			l := newStorageList()
+			fetched(l).RssItems[0].MarkRead()
+			if err := s.Save(l); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			counts, err := s.UnreadCounts()
+			if err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+			if counts[feedUrl] != 2 {
+				t.Errorf("got %d unread, want 2", counts[feedUrl])
+			}
+
+			searches := map[string][]string{
+				"go":             {"1", "3"},
+				"GENERIC":        {"1"},
+				"borrowing rust": {"2"},
+				"go rust":        nil,
+				`"comptime" (*`:  {"3"},
+				"":               nil,
+			}
+			for query, want := range searches {
+				got, err := s.Search(query)
+				if err != nil {
+					t.Fatalf("%q: unexpected error: %q", query, err)
+				}
+				slices.Sort(got)
+				if !slices.Equal(got, want) {
+					t.Errorf("%q: got %q, want %q", query, got, want)
+				}
+			}
+		})
+
+		t.Run(name+" should merge state saved by another instance", func(t *testing.T) {
+			dir := t.TempDir()
+
+			l := newStorageList()
+			fetched(l)
+			if err := open(t, dir).Save(l); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			a, b := newStorageList(), newStorageList()
+			sa, sb := open(t, dir), open(t, dir)
+			if err := sa.Load(a); err != nil {
+				t.Fatal(err)
+			}
+			if err := sb.Load(b); err != nil {
+				t.Fatal(err)
+			}
+
+			a.ItemIndex["1"].MarkRead()
+			if err := sa.Save(a); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+			b.ItemIndex["2"].MarkRead()
+			if err := sb.Save(b); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			if !b.ItemIndex["1"].Read {
+				t.Error("Expected the read state saved by a")
+			}
+
+			loaded := newStorageList()
+			if err := open(t, dir).Load(loaded); err != nil {
+				t.Fatal(err)
+			}
+			if !loaded.ItemIndex["1"].Read || !loaded.ItemIndex["2"].Read || loaded.ItemIndex["3"].Read {
+				t.Error("Expected the state of both instances to be saved")
+			}
+		})
+		t.Run(name+" should load items newest first after a second save", func(t *testing.T) {
+			dir := t.TempDir()
+			s := open(t, dir)
+
+			dated := func(guid string, published time.Time) *RssItem {
+				return &RssItem{Item: &gofeed.Item{GUID: guid, Title: guid,
+					Published: published.Format(time.RFC3339), PublishedParsed: &published}}
+			}
+
+			l := newStorageList()
+			feed := l.FeedIndex[feedUrl]
+			feed.RssItems = []*RssItem{dated("old", time.Unix(100, 0))}
+			if err := s.Save(l); err != nil {
+				t.Fatal(err)
+			}
+
+			feed.RssItems = append(feed.RssItems, dated("new", time.Unix(200, 0)))
+			feed.SortByDate()
+			if err := s.Save(l); err != nil {
+				t.Fatal(err)
+			}
+
+			loaded := newStorageList()
+			if err := open(t, dir).Load(loaded); err != nil {
+				t.Fatal(err)
+			}
+			items := loaded.FeedIndex[feedUrl].RssItems
+			if len(items) != 2 || items[0].GUID() != "new" || loaded.FeedIndex[feedUrl].LatestItem().GUID() != "new" {
+				t.Errorf("Expected the newest item first, got %+v", items)
+			}
+		})
+
+		t.Run(name+" should keep pruned items out", func(t *testing.T) {
+			dir := t.TempDir()
+
+			l := newStorageList()
+			fetched(l)
+			if err := open(t, dir).Save(l); err != nil {
+				t.Fatal(err)
+			}
+
+			a, b := newStorageList(), newStorageList()
+			sa, sb := open(t, dir), open(t, dir)
+			if err := sa.Load(a); err != nil {
+				t.Fatal(err)
+			}
+			if err := sb.Load(b); err != nil {
+				t.Fatal(err)
+			}
+
+			// b bookmarks what a prunes
+			a.Prune(RetentionConfig{MaxItems: 1}, time.Now())
+			if err := sa.Save(a); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+			b.ItemIndex["3"].ToggleBookmark()
+			b.SetBookmark(true, b.ItemIndex["3"])
+			if err := sb.Save(b); err != nil {
+				t.Fatalf("Unexpected error: %q", err)
+			}
+
+			if got := len(b.FeedIndex[feedUrl].RssItems); got != 2 || b.ItemIndex["2"] != nil {
+				t.Errorf("Expected the item a pruned to be dropped, got %d items", got)
+			}
+
+			loaded := newStorageList()
+			if err := open(t, dir).Load(loaded); err != nil {
+				t.Fatal(err)
+			}
+			feed := loaded.FeedIndex[feedUrl]
+			if len(feed.RssItems) != 2 || loaded.ItemIndex["1"] == nil || !loaded.ItemIndex["3"].Bookmark {
+				t.Errorf("got %+v", feed.RssItems)
+			}
+			if !feed.Pruned["2"] || feed.Pruned["3"] {
+				t.Errorf("got pruned %v", feed.Pruned)
+			}
+		})
+	}
+
+	t.Run("Should move data.json into sqlite once", func(t *testing.T) {
+		dir := t.TempDir()
+		jsonPath := filepath.Join(dir, "data.json")
+
+		l := newStorageList()
+		fetched(l).RssItems[2].MarkRead()
+		if err := NewJSONStorage(jsonPath).Save(l); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := OpenSQLiteStorage(filepath.Join(dir, "rssr.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		migrated, err := s.MigrateJSON(jsonPath)
+		if err != nil || !migrated {
+			t.Fatalf("Expected migration, got %v, %v", migrated, err)
+		}
+
+		loaded := newStorageList()
+		if err := s.Load(loaded); err != nil {
+			t.Fatal(err)
+		}
+		if len(loaded.FeedIndex[feedUrl].RssItems) != 3 || !loaded.ItemIndex["3"].Read {
+			t.Errorf("data.json not moved: %+v", loaded.FeedIndex[feedUrl].RssItems)
+		}
+
+		if err := os.Remove(jsonPath); err != nil {
+			t.Fatal(err)
+		}
+		if migrated, err := s.MigrateJSON(jsonPath); err != nil || migrated {
+			t.Errorf("Should only migrate once, got %v, %v", migrated, err)
+		}
+	})
+
+	t.Run("Should only write what changed to sqlite", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := OpenSQLiteStorage(filepath.Join(dir, "rssr.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		l := newStorageList()
+		fetched(l)
+		if err := s.Save(l); err != nil {
+			t.Fatal(err)
+		}
+
+		// Changed behind the storage, saving without a new Ts keeps it
+		if _, err := s.db.Exec("UPDATE items SET feed_title = 'untouched'"); err != nil {
+			t.Fatal(err)
+		}
+		l.ItemIndex["2"].MarkRead()
+		if err := s.Save(l); err != nil {
+			t.Fatal(err)
+		}
+
+		var untouched, read int
+		s.db.QueryRow("SELECT count(*) FROM items WHERE feed_title = 'untouched'").Scan(&untouched)
+		s.db.QueryRow("SELECT count(*) FROM items WHERE read = 1").Scan(&read)
+		if untouched != 3 || read != 1 {
+			t.Errorf("got %d untouched and %d read, want 3 and 1", untouched, read)
+		}
+	})
+}
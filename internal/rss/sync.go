@@ -19,17 +19,22 @@ type ItemState struct {
 	Bookmark bool
 }
 
-func (l *List) SyncList() error {
+// SyncList merges read and bookmark state with the sync server set in
+// config.yaml.
+func (l *List) SyncList(c SyncConfig) error {
+	if c.Url == "" {
+		return ErrSyncNotConfigured
+	}
+
 	l.ReindexList()
 
 	ls, err := l.SerializeList()
 	if err != nil {
 		return err
 	}
+	ls.ApiKey = c.ApiKey
 
-	//ls, err = SyncState("http://192.168.1.52:8080", ls)
-	//ls, err = SyncState("http://localhost:8080", ls)
-	ls, err = SyncState("http://alpine:8080", ls)
+	ls, err = SyncState(c.Url, ls)
 	if err != nil {
 		return err
 	}
@@ -39,7 +44,6 @@ func (l *List) SyncList() error {
 
 func (l *List) SerializeList() (*ListState, error) {
 	ls := &ListState{
-		ApiKey:    "localhost",
 		ItemIndex: make(map[string]*ItemState),
 	}
 	if len(l.Feeds) == 0 {
@@ -107,4 +107,9 @@ func TestSync(t *testing.T) {
 			t.Error("Bookmarks feed not updated")
 		}
 	})
+
+	t.Run("Should need a sync server", func(t *testing.T) {
+		l := &List{}
+		assertError(t, l.SyncList(SyncConfig{}), ErrSyncNotConfigured)
+	})
 }
@@ -0,0 +1,170 @@
+package rss
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/glamour/styles"
+)
+
+const (
+	ThemeAuto  = "auto"
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+	ThemeMono  = "mono"
+)
+
+// BuiltinThemes can be set as theme without defining them in config.yaml.
+var BuiltinThemes = []string{ThemeAuto, ThemeDark, ThemeLight, ThemeMono}
+
+// Theme sets the colors of the app and the style of item content. Colors
+// are ANSI color numbers or hex values, an empty color leaves the text in
+// the color of the terminal.
+type Theme struct {
+	// Base is the built in theme a theme from config.yaml starts from,
+	// auto when empty
+	Base     string `yaml:"base"`
+	Unread   string `yaml:"unread"`
+	Bookmark string `yaml:"bookmark"`
+	Error    string `yaml:"error"`
+	Title    string `yaml:"title"`
+	Status   string `yaml:"status"`
+	Tab      string `yaml:"tab"`
+	// Markdown is a glamour style name, or the path of a glamour JSON style
+	Markdown string `yaml:"markdown"`
+}
+
+var (
+	DarkTheme = Theme{
+		Base:     ThemeDark,
+		Unread:   "2",  // green
+		Bookmark: "11", // yellow
+		Error:    "9",  // red
+		Title:    "13", // magenta
+		Status:   "8",  // gray
+		Tab:      "7",  // light gray
+		Markdown: styles.DarkStyle,
+	}
+
+	LightTheme = Theme{
+		Base:     ThemeLight,
+		Unread:   "28",  // dark green
+		Bookmark: "136", // dark yellow
+		Error:    "160", // dark red
+		Title:    "127", // dark magenta
+		Status:   "244", // gray
+		Tab:      "238", // dark gray
+		Markdown: styles.LightStyle,
+	}
+
+	MonoTheme = Theme{
+		Base:     ThemeMono,
+		Markdown: styles.NoTTYStyle,
+	}
+)
+
+// ResolveTheme returns the theme set in config.yaml for a terminal with a
+// dark or light background. Auto picks dark or light by the background, or
+// mono when NO_COLOR is set.
+func (c *Config) ResolveTheme(dark bool) Theme {
+	name := c.Theme
+	custom, ok := c.Themes[name]
+	if ok {
+		name = custom.Base
+	}
+
+	t := builtinTheme(name, dark)
+	if !ok {
+		return t
+	}
+
+	for _, field := range []struct{ dst, src *string }{
+		{&t.Unread, &custom.Unread},
+		{&t.Bookmark, &custom.Bookmark},
+		{&t.Error, &custom.Error},
+		{&t.Title, &custom.Title},
+		{&t.Status, &custom.Status},
+		{&t.Tab, &custom.Tab},
+		{&t.Markdown, &custom.Markdown},
+	} {
+		if *field.src != "" {
+			*field.dst = *field.src
+		}
+	}
+	return t
+}
+
+func builtinTheme(name string, dark bool) Theme {
+	switch name {
+	case ThemeDark:
+		return DarkTheme
+	case ThemeLight:
+		return LightTheme
+	case ThemeMono:
+		return MonoTheme
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return MonoTheme
+	}
+	if dark {
+		return DarkTheme
+	}
+	return LightTheme
+}
+
+// validateTheme lists what is wrong with the theme defined as name.
+func validateTheme(name string, t Theme) []string {
+	var problems []string
+
+	if slices.Contains(BuiltinThemes, name) {
+		problems = append(problems, fmt.Sprintf("themes.%s: name is taken by a built in theme", name))
+	}
+
+	if t.Base != "" && !slices.Contains(BuiltinThemes, t.Base) {
+		problems = append(problems, fmt.Sprintf("themes.%s.base must be one of %s, got %q", name, strings.Join(BuiltinThemes, ", "), t.Base))
+	}
+
+	for _, color := range []struct{ key, value string }{
+		{"unread", t.Unread},
+		{"bookmark", t.Bookmark},
+		{"error", t.Error},
+		{"title", t.Title},
+		{"status", t.Status},
+		{"tab", t.Tab},
+	} {
+		if !validColor(color.value) {
+			problems = append(problems, fmt.Sprintf("themes.%s.%s must be a color number from 0 to 255 or a hex color, got %q", name, color.key, color.value))
+		}
+	}
+
+	if t.Markdown != "" {
+		if _, ok := styles.DefaultStyles[t.Markdown]; !ok {
+			if _, err := os.Stat(t.Markdown); err != nil {
+				problems = append(problems, fmt.Sprintf("themes.%s.markdown must be a glamour style or a style file, got %q", name, t.Markdown))
+			}
+		}
+	}
+
+	return problems
+}
+
+func validColor(c string) bool {
+	if c == "" {
+		return true
+	}
+
+	if hex, ok := strings.CutPrefix(c, "#"); ok {
+		if len(hex) != 3 && len(hex) != 6 {
+			return false
+		}
+		_, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil
+	}
+
+	n, err := strconv.Atoi(c)
+	return err == nil && n >= 0 && n <= 255
+}
@@ -0,0 +1,85 @@
+package rss
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTheme(t *testing.T) {
+	t.Run("Should pick theme by background", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "")
+		c := DefaultConfig()
+
+		if got := c.ResolveTheme(true); got != DarkTheme {
+			t.Errorf("Dark background should use dark theme, got %+v", got)
+		}
+		if got := c.ResolveTheme(false); got != LightTheme {
+			t.Errorf("Light background should use light theme, got %+v", got)
+		}
+	})
+
+	t.Run("Should use mono theme with NO_COLOR", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "1")
+		c := DefaultConfig()
+
+		if got := c.ResolveTheme(true); got != MonoTheme {
+			t.Errorf("NO_COLOR should use mono theme, got %+v", got)
+		}
+
+		c.Theme = ThemeLight
+		if got := c.ResolveTheme(true); got != LightTheme {
+			t.Errorf("Theme set in config should win over NO_COLOR, got %+v", got)
+		}
+	})
+
+	t.Run("Should start custom theme from base", func(t *testing.T) {
+		c := DefaultConfig()
+		c.Theme = "solarized"
+		c.Themes = map[string]Theme{
+			"solarized": {Base: ThemeLight, Unread: "#859900", Markdown: "dracula"},
+		}
+
+		if err := c.Validate(); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		got := c.ResolveTheme(true)
+		want := LightTheme
+		want.Unread = "#859900"
+		want.Markdown = "dracula"
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("Should report invalid themes", func(t *testing.T) {
+		c := DefaultConfig()
+		c.Themes = map[string]Theme{
+			"dark":  {},
+			"mine":  {Base: "blue", Error: "300", Title: "#12345", Markdown: "missing.json"},
+			"plain": {Unread: "#abc", Bookmark: "0"},
+		}
+
+		err := c.Validate()
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("got %q want %q", err, ErrInvalidConfig)
+		}
+
+		for _, want := range []string{
+			"themes.dark: name is taken",
+			"themes.mine.base",
+			`themes.mine.error must be a color number from 0 to 255 or a hex color, got "300"`,
+			"themes.mine.title",
+			"themes.mine.markdown",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Error should contain %s, got %q", want, err)
+			}
+		}
+
+		if strings.Contains(err.Error(), "themes.plain") {
+			t.Errorf("Valid theme should not be reported, got %q", err)
+		}
+	})
+}
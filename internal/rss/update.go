@@ -0,0 +1,285 @@
+package rss
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// UpdateOptions limits how many feeds are fetched at the same time and for
+// how long. Zero values mean no limit.
+type UpdateOptions struct {
+	// Concurrency is the maximum number of requests in flight.
+	Concurrency int
+	// PerHost is the maximum number of requests in flight to a single host,
+	// counted across all refreshes running at the same time.
+	PerHost int
+	// Timeout bounds a single feed, including the time spent reading the body.
+	Timeout time.Duration
+	// Deadline bounds the whole refresh. Feeds not finished by then are
+	// reported as cancelled.
+	Deadline time.Duration
+	// Force fetches feeds even when they are not due or backing off.
+	Force bool
+	// Rewrite changes the links of new items before they are stored.
+	Rewrite *Rewriter
+	// Retention prunes the items of feeds that changed.
+	Retention RetentionConfig
+}
+
+var DefaultUpdateOptions = UpdateOptions{
+	Concurrency: 16,
+	PerHost:     2,
+	Timeout:     30 * time.Second,
+	Deadline:    5 * time.Minute,
+}
+
+// UpdateOptions returns the default options with the retention settings of
+// the config, and its rewrite rules when they apply to stored links.
+func (c *Config) UpdateOptions() UpdateOptions {
+	opts := DefaultUpdateOptions
+	if c == nil {
+		return opts
+	}
+	if c.Rewrite.OnStore {
+		opts.Rewrite = c.Rewrite.Rewriter()
+	}
+	opts.Retention = c.Retention
+	return opts
+}
+
+func UpdateFeeds(feeds ...*RssFeed) (<-chan FeedResult, error) {
+	return UpdateFeedsWithOptions(DefaultUpdateOptions, feeds...)
+}
+
+func UpdateFeedsWithOptions(opts UpdateOptions, feeds ...*RssFeed) (<-chan FeedResult, error) {
+	return UpdateFeedsContext(context.Background(), opts, feeds...)
+}
+
+// UpdateFeedsContext refreshes feeds concurrently within the limits in
+// opts. Results are streamed as each feed finishes and the channel is closed
+// once all feeds are done. Cancelling ctx stops feeds that are still waiting
+// or downloading; they keep their previous state. Feeds that are already
+// refreshing are skipped.
+func UpdateFeedsContext(ctx context.Context, opts UpdateOptions, feeds ...*RssFeed) (<-chan FeedResult, error) {
+	if len(feeds) == 0 {
+		return nil, ErrNoFeedsInList
+	}
+
+	ctx, cancel := withTimeout(ctx, opts.Deadline)
+
+	results := make(chan FeedResult, len(feeds))
+	limit := newLimiter(opts, len(feeds))
+
+	var wg sync.WaitGroup
+	wg.Add(len(feeds))
+
+	for _, feed := range feeds {
+		// Marked before the goroutine starts, so WaitRefreshes sees it
+		if !feed.startRefresh() {
+			results <- FeedResult{Feed: feed, Skipped: true}
+			wg.Done()
+			continue
+		}
+
+		go func(f *RssFeed) {
+			defer wg.Done()
+			res := updateFeed(ctx, opts, limit, f)
+			f.endRefresh()
+			results <- res
+		}(feed)
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// refreshing holds the feeds with a refresh in flight, so a feed is never
+// fetched and merged by two refreshes at the same time.
+var refreshing = struct {
+	sync.Mutex
+	feeds map[*RssFeed]bool
+}{feeds: make(map[*RssFeed]bool)}
+
+// startRefresh marks f as refreshing, and reports false when it already was.
+func (f *RssFeed) startRefresh() bool {
+	refreshing.Lock()
+	defer refreshing.Unlock()
+
+	if refreshing.feeds[f] {
+		return false
+	}
+	refreshing.feeds[f] = true
+	return true
+}
+
+func (f *RssFeed) endRefresh() {
+	refreshing.Lock()
+	defer refreshing.Unlock()
+
+	delete(refreshing.feeds, f)
+}
+
+// WaitRefreshes waits up to timeout for the refreshes in flight to finish,
+// and reports whether they did. Cancel them first so it does not take long.
+func WaitRefreshes(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		refreshing.Lock()
+		n := len(refreshing.feeds)
+		refreshing.Unlock()
+
+		if n == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func updateFeed(ctx context.Context, opts UpdateOptions, limit *limiter, f *RssFeed) FeedResult {
+	// Cancelled before it started, leave the feed alone
+	if ctx.Err() != nil {
+		return FeedResult{Feed: f, Err: ctx.Err(), Cancelled: true}
+	}
+
+	now := time.Now()
+	if !opts.Force && f.waiting(now) {
+		return FeedResult{Feed: f, Err: ErrBackoff}
+	}
+	if !opts.Force && !f.due(now) {
+		return FeedResult{Feed: f, Skipped: true}
+	}
+
+	release, err := limit.acquire(ctx, f.host())
+	if err != nil {
+		return FeedResult{Feed: f, Err: err, Cancelled: true}
+	}
+	defer release()
+
+	prev := *f
+
+	feedCtx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	modified, err := f.refresh(feedCtx, time.Now(), opts.Rewrite)
+	if err != nil && ctx.Err() != nil {
+		f.Error, f.Failures, f.RetryAt = prev.Error, prev.Failures, prev.RetryAt
+		f.LastRefresh = prev.LastRefresh
+		return FeedResult{Feed: f, Err: ctx.Err(), Cancelled: true}
+	}
+	if modified {
+		opts.Retention.Prune(f, time.Now())
+	}
+
+	return FeedResult{Feed: f, Err: err, NotModified: err == nil && !modified}
+}
+
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+func (f *RssFeed) host() string {
+	u, err := url.Parse(f.Url)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// limiter hands out request slots. The host slot is taken before the global
+// one, so feeds queued behind a busy host do not hold up other hosts. Host
+// slots are shared by all refreshes, the global ones are per refresh.
+type limiter struct {
+	global  chan struct{}
+	perHost int
+}
+
+func newLimiter(opts UpdateOptions, n int) *limiter {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 || concurrency > n {
+		concurrency = n
+	}
+
+	perHost := opts.PerHost
+	if perHost <= 0 || perHost > concurrency {
+		perHost = concurrency
+	}
+
+	return &limiter{
+		global:  make(chan struct{}, concurrency),
+		perHost: perHost,
+	}
+}
+
+func (l *limiter) acquire(ctx context.Context, host string) (func(), error) {
+	if err := hosts.acquire(ctx, host, l.perHost); err != nil {
+		return nil, err
+	}
+
+	select {
+	case l.global <- struct{}{}:
+	case <-ctx.Done():
+		hosts.release(host)
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-l.global
+		hosts.release(host)
+	}, nil
+}
+
+// hosts counts the requests in flight to each host across all refreshes, so
+// refreshes running at the same time share the PerHost limit.
+var hosts = &hostSlots{inFlight: make(map[string]int), freed: make(chan struct{})}
+
+type hostSlots struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+	// freed is closed and replaced whenever a slot is released
+	freed chan struct{}
+}
+
+// acquire waits until fewer than limit requests to host are in flight and
+// takes a slot.
+func (h *hostSlots) acquire(ctx context.Context, host string, limit int) error {
+	for {
+		h.mu.Lock()
+		if h.inFlight[host] < limit {
+			h.inFlight[host]++
+			h.mu.Unlock()
+			return nil
+		}
+		freed := h.freed
+		h.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (h *hostSlots) release(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.inFlight[host]--; h.inFlight[host] <= 0 {
+		delete(h.inFlight, host)
+	}
+	close(h.freed)
+	h.freed = make(chan struct{})
+}
@@ -0,0 +1,287 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func ServerInFlight(t *testing.T, data []byte, inFlight, peak *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}))
+	return server
+}
+
+func ServerHanging(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	return server
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("Should limit requests per host", func(t *testing.T) {
+		var inFlight, peak atomic.Int32
+		server := ServerInFlight(t, testData(t, "feed.xml"), &inFlight, &peak)
+		defer server.Close()
+
+		feeds := make([]*RssFeed, 8)
+		for i := range feeds {
+			feeds[i] = &RssFeed{Url: server.URL}
+		}
+
+		opts := UpdateOptions{Concurrency: 8, PerHost: 2}
+		results, err := UpdateFeedsWithOptions(opts, feeds...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		received := 0
+		for res := range results {
+			received++
+			if res.Err != nil {
+				t.Errorf("Unexpected error: %q", res.Err)
+			}
+		}
+
+		if received != len(feeds) {
+			t.Errorf("expected %d results, got %d", len(feeds), received)
+		}
+
+		if peak.Load() > int32(opts.PerHost) {
+			t.Errorf("Too many requests to one host, wanted at most %d, got %d", opts.PerHost, peak.Load())
+		}
+	})
+
+	t.Run("Should share the host limit between refreshes", func(t *testing.T) {
+		var inFlight, peak atomic.Int32
+		server := ServerInFlight(t, testData(t, "feed.xml"), &inFlight, &peak)
+		defer server.Close()
+
+		opts := UpdateOptions{Concurrency: 4, PerHost: 1}
+		var all []<-chan FeedResult
+		for range 3 {
+			feeds := []*RssFeed{{Url: server.URL}, {Url: server.URL}}
+			results, err := UpdateFeedsWithOptions(opts, feeds...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			all = append(all, results)
+		}
+
+		for _, results := range all {
+			for res := range results {
+				if res.Err != nil {
+					t.Errorf("Unexpected error: %q", res.Err)
+				}
+			}
+		}
+
+		if peak.Load() > int32(opts.PerHost) {
+			t.Errorf("Too many requests to one host, wanted at most %d, got %d", opts.PerHost, peak.Load())
+		}
+	})
+
+	t.Run("Should limit requests globally", func(t *testing.T) {
+		var inFlight, peak atomic.Int32
+		serverA := ServerInFlight(t, testData(t, "feed.xml"), &inFlight, &peak)
+		defer serverA.Close()
+		serverB := ServerInFlight(t, testData(t, "feed.xml"), &inFlight, &peak)
+		defer serverB.Close()
+
+		feeds := []*RssFeed{
+			{Url: serverA.URL},
+			{Url: serverA.URL},
+			{Url: serverB.URL},
+			{Url: serverB.URL},
+		}
+
+		opts := UpdateOptions{Concurrency: 1, PerHost: 2}
+		results, err := UpdateFeedsWithOptions(opts, feeds...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for range results {
+		}
+
+		if peak.Load() > int32(opts.Concurrency) {
+			t.Errorf("Too many requests in flight, wanted at most %d, got %d", opts.Concurrency, peak.Load())
+		}
+	})
+
+	t.Run("Should not limit when options are zero", func(t *testing.T) {
+		limit := newLimiter(UpdateOptions{}, 3)
+
+		if cap(limit.global) != 3 || limit.perHost != 3 {
+			t.Errorf("Zero options should allow all feeds at once, got %d and %d", cap(limit.global), limit.perHost)
+		}
+	})
+
+	t.Run("Should not refresh a feed twice at the same time", func(t *testing.T) {
+		var inFlight, peak atomic.Int32
+		server := ServerInFlight(t, testData(t, "feed.xml"), &inFlight, &peak)
+		defer server.Close()
+
+		feed := &RssFeed{Url: server.URL}
+		opts := UpdateOptions{Force: true}
+
+		first, err := UpdateFeedsWithOptions(opts, feed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		second, err := UpdateFeedsWithOptions(opts, feed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		skipped := 0
+		for _, results := range []<-chan FeedResult{first, second} {
+			for res := range results {
+				if res.Err != nil {
+					t.Errorf("Unexpected error: %q", res.Err)
+				}
+				if res.Skipped {
+					skipped++
+				}
+			}
+		}
+
+		if skipped != 1 || peak.Load() != 1 {
+			t.Errorf("Expected one refresh and one skipped, got %d skipped and %d requests at once", skipped, peak.Load())
+		}
+		if err := feed.GetFeed(); err != nil {
+			t.Errorf("Should refresh again once done, got %q", err)
+		}
+	})
+
+	t.Run("Should wait for refreshes in flight", func(t *testing.T) {
+		server := ServerHanging(t)
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		results, err := UpdateFeedsContext(ctx, UpdateOptions{}, &RssFeed{Url: server.URL})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if WaitRefreshes(50 * time.Millisecond) {
+			t.Error("Should not be done while the feed hangs")
+		}
+		cancel()
+		if !WaitRefreshes(time.Second) {
+			t.Error("Should be done once cancelled")
+		}
+		for range results {
+		}
+	})
+
+	t.Run("Should time out a hanging feed", func(t *testing.T) {
+		server := ServerHanging(t)
+		defer server.Close()
+
+		feed := &RssFeed{Url: server.URL}
+		opts := UpdateOptions{Timeout: 50 * time.Millisecond}
+
+		results, err := UpdateFeedsWithOptions(opts, feed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		select {
+		case res := <-results:
+			if res.Err == nil {
+				t.Error("Hanging feed should return error")
+			}
+			if res.Cancelled {
+				t.Error("Per feed timeout should not be reported as cancelled")
+			}
+			if feed.Error == "" {
+				t.Error("Should store timeout error on feed")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for feed results")
+		}
+	})
+
+	t.Run("Should report cancelled feeds", func(t *testing.T) {
+		server := ServerHanging(t)
+		defer server.Close()
+
+		feeds := []*RssFeed{
+			{Url: server.URL, Error: "Previous error"},
+			{Url: server.URL, Error: "Previous error"},
+			{Url: server.URL, Error: "Previous error"},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		opts := UpdateOptions{PerHost: 1}
+
+		results, err := UpdateFeedsContext(ctx, opts, feeds...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+
+		cancelled := 0
+		for res := range results {
+			if res.Cancelled {
+				cancelled++
+			}
+		}
+
+		if cancelled != len(feeds) {
+			t.Errorf("expected %d cancelled feeds, got %d", len(feeds), cancelled)
+		}
+
+		for _, feed := range feeds {
+			if feed.Error != "Previous error" {
+				t.Errorf("Cancelled feed should keep previous state, got %q", feed.Error)
+			}
+		}
+	})
+
+	t.Run("Should cancel feeds after deadline", func(t *testing.T) {
+		server := ServerHanging(t)
+		defer server.Close()
+
+		feed := &RssFeed{Url: server.URL}
+		opts := UpdateOptions{Deadline: 50 * time.Millisecond}
+
+		results, err := UpdateFeedsWithOptions(opts, feed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		select {
+		case res := <-results:
+			if !res.Cancelled {
+				t.Error("Feed should be cancelled after deadline")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for feed results")
+		}
+	})
+}
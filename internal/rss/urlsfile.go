@@ -0,0 +1,442 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// UrlsFile edits urls.yaml line by line so comments and formatting written
+// by hand survive changes made from the app.
+type UrlsFile struct {
+	path  string
+	lines []string
+}
+
+// ImportCategory is used for imported feeds that have no folder or tag.
+const ImportCategory = "feeds"
+
+// Subscription is a feed to add to urls.yaml under a category, as read
+// from an import.
+type Subscription struct {
+	Category string
+	Settings FeedSettings
+}
+
+func NewUrlsFile(data []byte) *UrlsFile {
+	return &UrlsFile{lines: strings.Split(string(data), "\n")}
+}
+
+// ReadUrlsFile reads urls.yaml at path. A missing file is an empty one.
+func ReadUrlsFile(path string) (*UrlsFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	u := NewUrlsFile(data)
+	u.path = path
+	return u, nil
+}
+
+func (u *UrlsFile) Bytes() []byte {
+	return []byte(strings.Join(u.lines, "\n"))
+}
+
+func (u *UrlsFile) Save() error {
+	return os.WriteFile(u.path, u.Bytes(), 0644)
+}
+
+// Add appends the feed to the end of the category, creating the category
+// at the end of the file when it does not exist yet.
+func (u *UrlsFile) Add(category string, s FeedSettings) error {
+	start, ok, err := u.findCategory(category)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		key, err := yamlLine(yaml.MapSlice{{Key: category, Value: nil}})
+		if err != nil {
+			return err
+		}
+
+		entry, err := entryLines(s, "  ")
+		if err != nil {
+			return err
+		}
+
+		// Keep the final newline of the file after the new category
+		end := len(u.lines)
+		if end > 0 && u.lines[end-1] == "" {
+			end--
+		}
+
+		var block []string
+		if end > 0 && strings.TrimSpace(u.lines[end-1]) != "" {
+			block = append(block, "")
+		}
+		block = append(block, strings.TrimSuffix(key, " null"))
+		block = append(block, entry...)
+
+		u.insert(end, block...)
+		return nil
+	}
+
+	last, indent := u.blockEnd(start)
+	entry, err := entryLines(s, indent)
+	if err != nil {
+		return err
+	}
+
+	u.insert(last+1, entry...)
+	return nil
+}
+
+// Merge adds every subscription whose URL is not in the list or earlier in
+// subs, and returns how many were added.
+func (u *UrlsFile) Merge(l *List, subs []Subscription) (int, error) {
+	seen := make(map[string]struct{})
+	for url := range l.FeedIndex {
+		seen[url] = struct{}{}
+	}
+
+	added := 0
+	for _, sub := range subs {
+		if sub.Settings.Url == "" {
+			continue
+		}
+		if _, ok := seen[sub.Settings.Url]; ok {
+			continue
+		}
+
+		if err := u.Add(sub.Category, sub.Settings); err != nil {
+			return added, err
+		}
+		seen[sub.Settings.Url] = struct{}{}
+		added++
+	}
+
+	return added, nil
+}
+
+// Remove deletes every entry of the URL and returns their settings. Comments
+// around the entries are kept.
+func (u *UrlsFile) Remove(url string) ([]FeedSettings, error) {
+	var removed []FeedSettings
+	entries := u.entries()
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.settings.Url != url {
+			continue
+		}
+		u.lines = append(u.lines[:e.start], u.lines[e.end+1:]...)
+		removed = append([]FeedSettings{e.settings}, removed...)
+	}
+
+	if len(removed) == 0 {
+		return nil, ErrFeedNotFound
+	}
+	return removed, nil
+}
+
+// Move moves the URL from every category it is in to the end of category,
+// keeping the settings of its first entry.
+func (u *UrlsFile) Move(url, category string) error {
+	removed, err := u.Remove(url)
+	if err != nil {
+		return err
+	}
+	return u.Add(category, removed[0])
+}
+
+// Replace changes the URL of every entry of old to new, leaving the rest of
+// the entries as they are.
+func (u *UrlsFile) Replace(old, new string) error {
+	replaced := false
+	for _, e := range u.entries() {
+		if e.settings.Url != old {
+			continue
+		}
+		for i := e.start; i <= e.end; i++ {
+			if strings.Contains(u.lines[i], old) {
+				u.lines[i] = strings.Replace(u.lines[i], old, new, 1)
+				replaced = true
+				break
+			}
+		}
+	}
+
+	if !replaced {
+		return ErrFeedNotFound
+	}
+	return nil
+}
+
+// urlsEntry is a feed in a category of urls.yaml, from line start to end.
+type urlsEntry struct {
+	category   string
+	start, end int
+	settings   FeedSettings
+}
+
+// entries lists the feeds of every category in file order. Entries that do
+// not parse are left out.
+func (u *UrlsFile) entries() []urlsEntry {
+	var entries []urlsEntry
+	category := ""
+	itemIndent := -1
+
+	for i := 0; i < len(u.lines); i++ {
+		line := u.lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if isKeyLine(line) {
+			var key yaml.MapSlice
+			category = ""
+			if err := yaml.Unmarshal([]byte(line), &key); err == nil && len(key) == 1 {
+				category = fmt.Sprint(key[0].Key)
+			}
+			itemIndent = -1
+			continue
+		}
+
+		if category == "" || !strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if itemIndent == -1 {
+			itemIndent = indent
+		}
+		if indent != itemIndent {
+			continue
+		}
+
+		end := i
+		for j := i + 1; j < len(u.lines); j++ {
+			next := u.lines[j]
+			t := strings.TrimSpace(next)
+			if t == "" || strings.HasPrefix(t, "#") {
+				continue
+			}
+			if len(next)-len(strings.TrimLeft(next, " \t")) <= indent {
+				break
+			}
+			end = j
+		}
+
+		var text []string
+		for _, l := range u.lines[i : end+1] {
+			if len(l) >= indent {
+				l = l[indent:]
+			}
+			text = append(text, l)
+		}
+
+		var parsed []FeedSettings
+		if err := yaml.Unmarshal([]byte(strings.Join(text, "\n")), &parsed); err == nil && len(parsed) == 1 {
+			entries = append(entries, urlsEntry{category, i, end, parsed[0]})
+		}
+		i = end
+	}
+
+	return entries
+}
+
+func (u *UrlsFile) insert(at int, lines ...string) {
+	u.lines = append(u.lines[:at], append(lines, u.lines[at:]...)...)
+}
+
+// findCategory returns the line of the top level key for the category.
+func (u *UrlsFile) findCategory(category string) (int, bool, error) {
+	for i, line := range u.lines {
+		if !isKeyLine(line) {
+			continue
+		}
+
+		var key yaml.MapSlice
+		if err := yaml.Unmarshal([]byte(line), &key); err != nil || len(key) != 1 {
+			continue
+		}
+
+		if fmt.Sprint(key[0].Key) != category {
+			continue
+		}
+
+		if key[0].Value != nil {
+			return i, true, ErrUrlsFileFlowStyle
+		}
+		return i, true, nil
+	}
+	return 0, false, nil
+}
+
+// blockEnd returns the last entry line of the category starting at start,
+// and the indentation its entries use.
+func (u *UrlsFile) blockEnd(start int) (int, string) {
+	last := start
+	indent := ""
+	found := false
+
+	for i := start + 1; i < len(u.lines); i++ {
+		line := u.lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if isKeyLine(line) {
+			break
+		}
+
+		last = i
+		if !found && strings.HasPrefix(trimmed, "-") {
+			indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			found = true
+		}
+	}
+
+	if !found {
+		indent = "  "
+	}
+	return last, indent
+}
+
+// isKeyLine reports whether the line starts a top level key.
+func isKeyLine(line string) bool {
+	if line == "" || strings.TrimSpace(line) == "" {
+		return false
+	}
+	switch line[0] {
+	case ' ', '\t', '#', '-':
+		return false
+	}
+	return true
+}
+
+// entryLines renders a single list entry with the given indentation.
+func entryLines(s FeedSettings, indent string) ([]string, error) {
+	out, err := yamlLine([]FeedSettings{s})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sequences are indented, drop the indentation of the outer one
+	lines := strings.Split(out, "\n")
+	for i := range lines {
+		lines[i] = indent + strings.TrimPrefix(lines[i], "  ")
+	}
+	return lines, nil
+}
+
+func yamlLine(v interface{}) (string, error) {
+	out, err := yaml.MarshalWithOptions(v, yaml.Indent(2), yaml.IndentSequence(true))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// ImportSubscriptions merges the subscriptions into urls.yaml, skipping URLs
+// that are already in l, and returns how many were added.
+func ImportSubscriptions(l *List, subs []Subscription) (int, error) {
+	u, err := OpenUrlsFile()
+	if err != nil {
+		return 0, err
+	}
+
+	added, err := u.Merge(l, subs)
+	if err != nil || added == 0 {
+		return added, err
+	}
+
+	return added, u.Save()
+}
+
+// AddSubscription adds the feed to urls.yaml and to the list.
+func AddSubscription(l *List, category string, s FeedSettings) (*RssFeed, error) {
+	if category == "" {
+		return nil, ErrNoCategoryGiven
+	}
+	if feed := l.FeedIndex[s.Url]; feed != nil && slices.Contains(feed.categories(), category) {
+		return nil, ErrFeedExists
+	}
+
+	u, err := OpenUrlsFile()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.Add(category, s); err != nil {
+		return nil, err
+	}
+	if err := u.Save(); err != nil {
+		return nil, err
+	}
+
+	return l.AddFeed(category, s), nil
+}
+
+// RemoveSubscription removes the feed from urls.yaml and from the list.
+func RemoveSubscription(l *List, url string) error {
+	u, err := OpenUrlsFile()
+	if err != nil {
+		return err
+	}
+	if _, err := u.Remove(url); err != nil {
+		return err
+	}
+	if err := u.Save(); err != nil {
+		return err
+	}
+
+	l.RemoveFeed(url)
+	return nil
+}
+
+// MoveSubscription moves the feed to category in urls.yaml and in the list.
+func MoveSubscription(l *List, url, category string) error {
+	if category == "" {
+		return ErrNoCategoryGiven
+	}
+
+	u, err := OpenUrlsFile()
+	if err != nil {
+		return err
+	}
+	if err := u.Move(url, category); err != nil {
+		return err
+	}
+	if err := u.Save(); err != nil {
+		return err
+	}
+
+	return l.MoveFeed(url, category)
+}
+
+// ReplaceSubscription changes the URL of a feed in urls.yaml and in the
+// list, keeping its settings and items.
+func ReplaceSubscription(l *List, old, new string) error {
+	if l.FeedIndex[new] != nil {
+		return ErrFeedExists
+	}
+
+	u, err := OpenUrlsFile()
+	if err != nil {
+		return err
+	}
+	if err := u.Replace(old, new); err != nil {
+		return err
+	}
+	if err := u.Save(); err != nil {
+		return err
+	}
+
+	return l.ReplaceFeed(old, new)
+}
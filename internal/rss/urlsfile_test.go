@@ -0,0 +1,198 @@
+package rss
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestUrlsFile(t *testing.T) {
+	urls := `# My feeds
+golang:
+  - https://go.dev/blog/feed.atom
+  # the weekly one
+  - https://cprss.s3.amazonaws.com/golangweekly.com.xml
+
+# jobs last
+jobs:
+    - https://golang.cafe/rss
+`
+
+	t.Run("Should add feed to existing category", func(t *testing.T) {
+		u := NewUrlsFile([]byte(urls))
+
+		if err := u.Add("golang", FeedSettings{Url: "https://research.swtch.com/feed.atom"}); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if err := u.Add("jobs", FeedSettings{Url: "https://example.com/jobs", Title: "Jobs: Go"}); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := `# My feeds
+golang:
+  - https://go.dev/blog/feed.atom
+  # the weekly one
+  - https://cprss.s3.amazonaws.com/golangweekly.com.xml
+  - https://research.swtch.com/feed.atom
+
+# jobs last
+jobs:
+    - https://golang.cafe/rss
+    - url: https://example.com/jobs
+      title: "Jobs: Go"
+`
+		if got := string(u.Bytes()); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("Should add new category at the end", func(t *testing.T) {
+		u := NewUrlsFile([]byte(urls))
+
+		if err := u.Add("news", FeedSettings{Url: "https://example.com/news"}); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := urls + "\nnews:\n  - https://example.com/news\n"
+		if got := string(u.Bytes()); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("Should add to empty file", func(t *testing.T) {
+		u := NewUrlsFile(nil)
+
+		if err := u.Add("feeds", FeedSettings{Url: "https://example.com/feed"}); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := "feeds:\n  - https://example.com/feed\n"
+		if got := string(u.Bytes()); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Should refuse flow style category", func(t *testing.T) {
+		u := NewUrlsFile([]byte("golang: [https://go.dev/blog/feed.atom]\n"))
+
+		err := u.Add("golang", FeedSettings{Url: "https://example.com/feed"})
+		assertError(t, err, ErrUrlsFileFlowStyle)
+	})
+
+	t.Run("Should merge without duplicates", func(t *testing.T) {
+		u := NewUrlsFile([]byte(urls))
+		l := NewListWithDefaults()
+		if err := l.CreateFeedsFromYaml(fstest.MapFS{"urls.yaml": {Data: []byte(urls)}}, "urls.yaml"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		subs := []Subscription{
+			{"golang", FeedSettings{Url: "https://go.dev/blog/feed.atom"}},
+			{"news", FeedSettings{Url: "https://example.com/news"}},
+			{"other", FeedSettings{Url: "https://example.com/news"}},
+		}
+
+		added, err := u.Merge(l, subs)
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if added != 1 {
+			t.Errorf("got %d added, want 1", added)
+		}
+
+		merged := NewListWithDefaults()
+		if err := merged.CreateFeedsFromYaml(fstest.MapFS{"urls.yaml": {Data: u.Bytes()}}, "urls.yaml"); err != nil {
+			t.Fatalf("Merged file does not parse: %q", err)
+		}
+		if len(merged.Feeds) != len(l.Feeds)+1 {
+			t.Errorf("got %d feeds, want %d", len(merged.Feeds), len(l.Feeds)+1)
+		}
+	})
+
+	t.Run("Should remove feed and keep comments", func(t *testing.T) {
+		u := NewUrlsFile([]byte(`golang:
+  # the blog
+  - https://go.dev/blog/feed.atom
+  - url: https://www.reddit.com/r/golang.rss
+    # shown as r/golang
+    title: r/golang
+    tags:
+      - reddit
+  # keep me
+  - https://research.swtch.com/feed.atom
+work:
+  - https://www.reddit.com/r/golang.rss
+`))
+
+		removed, err := u.Remove("https://www.reddit.com/r/golang.rss")
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+		if len(removed) != 2 || removed[0].Title != "r/golang" {
+			t.Errorf("Unexpected removed entries: %+v", removed)
+		}
+
+		want := `golang:
+  # the blog
+  - https://go.dev/blog/feed.atom
+  # keep me
+  - https://research.swtch.com/feed.atom
+work:
+`
+		if got := string(u.Bytes()); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+
+		_, err = u.Remove("https://example.com/missing")
+		assertError(t, err, ErrFeedNotFound)
+	})
+
+	t.Run("Should move feed with its settings", func(t *testing.T) {
+		u := NewUrlsFile([]byte(`golang:
+  - url: https://go.dev/blog/feed.atom
+    title: Go
+  - https://research.swtch.com/feed.atom
+jobs:
+  - https://golang.cafe/rss
+`))
+
+		if err := u.Move("https://go.dev/blog/feed.atom", "jobs"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := `golang:
+  - https://research.swtch.com/feed.atom
+jobs:
+  - https://golang.cafe/rss
+  - url: https://go.dev/blog/feed.atom
+    title: Go
+`
+		if got := string(u.Bytes()); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("Should replace feed URL", func(t *testing.T) {
+		u := NewUrlsFile([]byte(`golang:
+  - https://research.swtch.com # the page, not the feed
+work:
+  - url: https://research.swtch.com
+    title: rsc
+`))
+
+		if err := u.Replace("https://research.swtch.com", "https://research.swtch.com/feed.atom"); err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		want := `golang:
+  - https://research.swtch.com/feed.atom # the page, not the feed
+work:
+  - url: https://research.swtch.com/feed.atom
+    title: rsc
+`
+		if got := string(u.Bytes()); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+
+		assertError(t, u.Replace("https://example.com", "https://example.com/feed"), ErrFeedNotFound)
+	})
+}
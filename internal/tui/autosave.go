@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// A run of changes is saved once they stop for autosaveDelay, or once the
+// oldest unsaved change is autosaveMaxDelay old
+const (
+	autosaveDelay    = 2 * time.Second
+	autosaveMaxDelay = 30 * time.Second
+)
+
+// quitRefreshWait is how long quitting waits for cancelled refreshes to
+// stop writing to the feeds before saving
+const quitRefreshWait = 5 * time.Second
+
+type autosaveMsg struct {
+	gen int
+}
+
+// changed records a change to read state, bookmarks or feeds and schedules
+// a save.
+func (m *model) changed() tea.Cmd {
+	if !m.unsaved {
+		m.unsaved = true
+		m.unsavedSince = time.Now()
+	}
+	m.saveGen++
+
+	gen := m.saveGen
+	return tea.Tick(autosaveDelay, func(time.Time) tea.Msg {
+		return autosaveMsg{gen: gen}
+	})
+}
+
+func handleAutosave(m *model, msg autosaveMsg) tea.Cmd {
+	if !m.unsaved {
+		return nil
+	}
+	// Refreshes write to the feeds being saved, resumeAutosave saves once
+	// they are done
+	if m.refreshes > 0 {
+		return nil
+	}
+	if msg.gen != m.saveGen && time.Since(m.unsavedSince) < autosaveMaxDelay {
+		return nil
+	}
+
+	if err := m.SaveState(); err != nil {
+		m.UpdateStatus(fmt.Sprintf("%s: %v", ErrSavingState, err))
+		return nil
+	}
+
+	// Saving merges what other instances saved, show it
+	if m.f != nil {
+		refreshItemsList(m)
+	}
+	return rebuildFeedList(m)
+}
+
+// resumeAutosave schedules the save held back while feeds were refreshing.
+func (m *model) resumeAutosave() tea.Cmd {
+	if !m.unsaved || m.refreshes > 0 {
+		return nil
+	}
+	return m.changed()
+}
+
+// saveForReload saves the list before it is loaded again from disk. Like
+// autosave, it is held while refreshes write to the feeds, the status says
+// why.
+func (m *model) saveForReload() bool {
+	if m.refreshes > 0 {
+		m.UpdateStatus(MsgWaitForRefresh)
+		return false
+	}
+	if err := m.SaveState(); err != nil {
+		m.UpdateStatus(fmt.Sprintf("%s: %v", ErrSavingState, err))
+		return false
+	}
+	return true
+}
+
+// flush saves changes that were not saved yet.
+func (m *model) flush() error {
+	if !m.unsaved {
+		return nil
+	}
+	return m.SaveState()
+}
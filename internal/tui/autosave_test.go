@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutosave(t *testing.T) {
+	t.Run("Should schedule a save for every change", func(t *testing.T) {
+		m := &model{}
+
+		if cmd := m.changed(); cmd == nil {
+			t.Fatal("Expected a save to be scheduled")
+		}
+		first := m.unsavedSince
+		m.changed()
+
+		if !m.unsaved {
+			t.Error("Expected unsaved changes")
+		}
+		if m.saveGen != 2 {
+			t.Errorf("got generation %d, want 2", m.saveGen)
+		}
+		if !m.unsavedSince.Equal(first) {
+			t.Error("Later changes should not move the time of the first one")
+		}
+	})
+
+	t.Run("Should wait for the last change", func(t *testing.T) {
+		m := &model{}
+		m.changed()
+		m.changed()
+
+		handleAutosave(m, autosaveMsg{gen: 1})
+
+		if !m.unsaved {
+			t.Error("Should not save while changes keep coming")
+		}
+	})
+
+	t.Run("Should wait for refreshes in flight", func(t *testing.T) {
+		m := &model{refreshes: 1}
+		m.changed()
+
+		// Saving would need a list, waiting does not
+		handleAutosave(m, autosaveMsg{gen: m.saveGen})
+		if !m.unsaved {
+			t.Error("Should not save while feeds are refreshing")
+		}
+		if m.resumeAutosave() != nil {
+			t.Error("Should not resume while feeds are refreshing")
+		}
+
+		m.refreshes--
+		if m.resumeAutosave() == nil {
+			t.Error("Expected a save to be scheduled once refreshes are done")
+		}
+	})
+
+	t.Run("Should not save without changes", func(t *testing.T) {
+		m := &model{unsavedSince: time.Now().Add(-time.Hour)}
+
+		handleAutosave(m, autosaveMsg{gen: 0})
+		if err := m.flush(); err != nil {
+			t.Errorf("Unexpected error: %q", err)
+		}
+	})
+	t.Run("Should hold saves for a reload while refreshing", func(t *testing.T) {
+		m := &model{refreshes: 1}
+		t.Cleanup(func() { m.clearTimer.Stop() })
+
+		if m.saveForReload() || m.status != MsgWaitForRefresh {
+			t.Errorf("Should not save while feeds are refreshing, got status %q", m.status)
+		}
+	})
+
+	t.Run("Should leave saving to the final flush on interrupt", func(t *testing.T) {
+		m := &model{}
+
+		if handleInterrupt(m) == nil || !m.unsaved {
+			t.Error("Expected to quit with the save left to flush")
+		}
+	})
+}
@@ -1,82 +1,15 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
 	"slices"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"github.com/emilosman/rssr/internal/rss"
-	"github.com/muesli/reflow/wordwrap"
-)
-
-type keyHandler func(*model) tea.Cmd
-
-var (
-	feedKeyHandlers = map[string]keyHandler{
-		"A":      handleMarkFeedRead,
-		"b":      handlePrevUnreadFeed,
-		"B":      handleViewBookmarks,
-		"E":      handleEdit,
-		"h":      handlePrevTab,
-		"l":      handleNextTab,
-		"n":      handleNextUnreadFeed,
-		"o":      handleOpenLatest,
-		"O":      handleOpenFeed,
-		"p":      handlePrevUnreadFeed,
-		"q":      handleQuit,
-		"r":      handleUpdateFeed,
-		"R":      handleUpdateAllFeeds,
-		"enter":  handleEnterFeed,
-		"esc":    handleQuit,
-		"tab":    handleNextTab,
-		"left":   handlePrevTab,
-		"right":  handleNextTab,
-		"ctrl+a": handleMarkTabAsRead,
-		"ctrl+c": handleInterrupt,
-		"ctrl+r": handleTabUpdate,
-	}
-
-	itemKeyHandlers = map[string]keyHandler{
-		"a":      handleToggleRead,
-		"A":      handleMarkItemsRead,
-		"b":      handleBack,
-		"B":      handleViewBookmarks,
-		"c":      handleToggleBookmark,
-		"n":      handleNextUnreadItem,
-		"o":      handleOpenItem,
-		"O":      handleOpenFeed,
-		"p":      handlePrevUnreadItem,
-		"q":      handleBack,
-		"r":      handleUpdateFeed,
-		"R":      handleUpdateAllFeeds,
-		"enter":  handleViewItem,
-		"esc":    handleBack,
-		"ctrl+c": handleInterrupt,
-	}
-
-	viewKeyHandlers = map[string]keyHandler{
-		"a":      handleToggleRead,
-		"b":      handleBack,
-		"B":      handleViewBookmarks,
-		"c":      handleToggleBookmark,
-		"g":      handleGoToStart,
-		"h":      handleViewPrev,
-		"l":      handleViewNext,
-		"n":      handleNextUnreadItem,
-		"o":      handleOpenItem,
-		"p":      handlePrevUnreadItem,
-		"q":      handleBack,
-		"?":      handleViewHelp,
-		"ctrl+c": handleInterrupt,
-		"enter":  handleOpenItem,
-		"esc":    handleBack,
-		"left":   handleViewPrev,
-		"right":  handleViewNext,
-	}
 )
 
 func handleEdit(m *model) tea.Cmd {
@@ -87,38 +20,88 @@ func handleEdit(m *model) tea.Cmd {
 	}
 	urlsFile := filepath.Join(urlsFilePath, "urls.yaml")
 
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		switch runtime.GOOS {
-		case "windows":
-			editor = "notepad"
-		default:
-			editor = "nvim"
-		}
+	if !m.saveForReload() {
+		return nil
+	}
+	if err := editFile(m, urlsFile); err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
 	}
 
-	m.SaveState()
+	return reloadList(m, "URLs file edited")
+}
+
+func handleEditConfig(m *model) tea.Cmd {
+	configFilePath, err := rss.ConfigFilePath()
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+
+	if err := editFile(m, filepath.Join(configFilePath, "config.yaml")); err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+
+	return reloadConfig(m)
+}
+
+// editFile runs the editor on path and waits for it to close.
+func editFile(m *model, path string) error {
 	m.prog.ReleaseTerminal()
-	cmd := exec.Command(editor, urlsFile)
+	defer m.prog.RestoreTerminal()
 
+	cmd := editorCommand(m.cfg.Editor, path)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	err = cmd.Run()
+	return cmd.Run()
+}
+
+// reloadConfig loads config.yaml again after it changed. An invalid config
+// is reported and the current one is kept.
+func reloadConfig(m *model) tea.Cmd {
+	cfg, keys, err := loadConfig()
 	if err != nil {
 		m.UpdateStatus(err.Error())
 		return nil
 	}
 
-	m.prog.RestoreTerminal()
-	filesystem := os.DirFS(urlsFilePath)
-	l, err := rss.LoadList(filesystem)
+	m.cfg = cfg
+	m.keys = keys
+	m.l.SortCategories = cfg.SortCategories
+	cmds := []tea.Cmd{refreshTabs(m, activeTab(m.tabs, m.activeTab)), m.applyTheme()}
+	if !m.autoRefreshTicking {
+		cmds = append(cmds, autoRefreshTick(m))
+	}
+
+	m.UpdateStatus(MsgConfigReloaded)
+	return tea.Batch(cmds...)
+}
+
+// reloadList loads urls.yaml again after it changed and keeps the active
+// tab where possible.
+func reloadList(m *model, status string) tea.Cmd {
+	urlsFilePath, err := rss.UrlsFilePath()
 	if err != nil {
 		m.UpdateStatus(err.Error())
 		return nil
 	}
 
+	filesystem := os.DirFS(urlsFilePath)
+	l, err := rss.LoadListFrom(filesystem, m.l.Storage)
+	if err != nil && !errors.Is(err, rss.ErrDataFileRestored) {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+	if err != nil {
+		status = err.Error()
+	}
+
+	if m.cfg != nil {
+		l.SortCategories = m.cfg.SortCategories
+	}
 	m.l = l
 	m.tabs = l.Categories()
 
@@ -128,7 +111,7 @@ func handleEdit(m *model) tea.Cmd {
 		m.activeTab = len(m.tabs) - 1
 	}
 
-	m.UpdateStatus("URLs file edited")
+	m.UpdateStatus(status)
 
 	return rebuildFeedList(m)
 }
@@ -155,6 +138,7 @@ func handleToggleRead(m *model) tea.Cmd {
 		} else {
 			m.UpdateStatus(MsgMarkItemUnread)
 		}
+		return m.changed()
 	}
 	return nil
 }
@@ -179,7 +163,7 @@ func handleToggleBookmark(m *model) tea.Cmd {
 	}
 
 	rebuildItemsList(m)
-	return nil
+	return m.changed()
 }
 
 func handleViewBookmarks(m *model) tea.Cmd {
@@ -208,7 +192,7 @@ func handleNextUnreadItem(m *model) tea.Cmd {
 		}
 	}
 	if m.i != nil {
-		handleViewItem(m)
+		return handleViewItem(m)
 	}
 	return nil
 }
@@ -219,6 +203,7 @@ func handleMarkFeedRead(m *model) tea.Cmd {
 		f.MarkAllItemsRead()
 		rebuildFeedList(m)
 		m.UpdateStatus(MsgMarkFeedRead)
+		return m.changed()
 	}
 	return nil
 }
@@ -228,6 +213,7 @@ func handleMarkItemsRead(m *model) tea.Cmd {
 		m.f.MarkAllItemsRead()
 		rebuildItemsList(m)
 		m.UpdateStatus(MsgMarkFeedRead)
+		return m.changed()
 	}
 	return nil
 }
@@ -242,7 +228,7 @@ func handleMarkTabAsRead(m *model) tea.Cmd {
 	rebuildFeedList(m)
 	m.UpdateStatus(MsgMakrTabAsRead)
 
-	return nil
+	return m.changed()
 }
 
 func handleBack(m *model) tea.Cmd {
@@ -259,59 +245,48 @@ func handleBack(m *model) tea.Cmd {
 
 func handleOpenFeed(m *model) tea.Cmd {
 	i, ok := m.lf.SelectedItem().(feedItem)
-	if ok {
-		f := i.rssFeed
-		url, err := f.Link()
-		if err != nil {
-			m.UpdateStatus(err.Error())
-		}
+	if !ok {
+		return nil
+	}
 
-		err = openInBrowser(url)
-		if err != nil {
-			m.UpdateStatus(err.Error())
-		}
+	f := i.rssFeed
+	url, err := f.Link()
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
 	}
-	return nil
+
+	return openLink(m, rss.Link{Url: url, Title: f.Name(), Feed: f})
 }
 
 func handleOpenLatest(m *model) tea.Cmd {
 	i, ok := m.lf.SelectedItem().(feedItem)
-	if ok {
-		f := i.rssFeed
-
-		latest := f.LatestItem()
-		if latest == nil {
-			return nil
-		}
-
-		url := latest.Link()
-
-		err := openInBrowser(url)
-		if err != nil {
-			m.UpdateStatus(err.Error())
-		}
+	if !ok {
+		return nil
+	}
 
-		latest.MarkRead()
-		rebuildFeedList(m)
+	f := i.rssFeed
+	latest := f.LatestItem()
+	if latest == nil {
+		return nil
 	}
-	return nil
+
+	cmd := openLink(m, itemLink(m, latest))
+	latest.MarkRead()
+	rebuildFeedList(m)
+	return tea.Batch(cmd, m.changed())
 }
 
 func handleOpenItem(m *model) tea.Cmd {
 	i, ok := m.li.SelectedItem().(rssListItem)
-	if ok {
-		rssItem := i.item
-		if rssItem.Item != nil {
-			err := openInBrowser(rssItem.Link())
-			if err != nil {
-				errorMessage := fmt.Sprintf("Error opening item, %q", err)
-				m.UpdateStatus(errorMessage)
-			}
-			rssItem.MarkRead()
-			rebuildItemsList(m)
-		}
+	if !ok || i.item.Item == nil {
+		return nil
 	}
-	return nil
+
+	cmd := openLink(m, itemLink(m, i.item))
+	i.item.MarkRead()
+	rebuildItemsList(m)
+	return tea.Batch(cmd, m.changed())
 }
 
 func handlePrevUnreadItem(m *model) tea.Cmd {
@@ -324,7 +299,7 @@ func handlePrevUnreadItem(m *model) tea.Cmd {
 		}
 	}
 	if m.i != nil {
-		handleViewItem(m)
+		return handleViewItem(m)
 	}
 	return nil
 }
@@ -349,16 +324,27 @@ func handleUpdateFeed(m *model) tea.Cmd {
 
 func handleUpdateAllFeeds(m *model) tea.Cmd {
 	m.UpdateStatus(MsgUpdatingAllFeeds)
-	return updateAllFeedsCmd(m)
+	ctx, id := m.startRefresh()
+	return updateAllFeedsCmd(m, ctx, id)
 }
 
 func handleTabUpdate(m *model) tea.Cmd {
 	m.UpdateStatus(MsgUpdatingAllFeeds)
-	return updateTabFeedsCmd(m)
+	ctx, id := m.startRefresh()
+	return updateTabFeedsCmd(m, ctx, id)
 }
 
+func handleCancelRefresh(m *model) tea.Cmd {
+	if m.cancelRefresh != nil {
+		m.cancelRefresh()
+		m.UpdateStatus(MsgCancellingRefresh)
+	}
+	return nil
+}
+
+// handleQuit leaves saving to BuildApp, once refreshes have stopped
 func handleQuit(m *model) tea.Cmd {
-	m.SaveState()
+	m.unsaved = true
 	return tea.Quit
 }
 
@@ -379,17 +365,18 @@ func handleViewItem(m *model) tea.Cmd {
 	if ok {
 		m.i = i.item
 		if m.i.Item != nil {
-			m.v.SetContent(wordwrap.String(m.i.Content(), 80))
+			setViewContent(m, m.i)
 			m.i.MarkRead()
 			rebuildItemsList(m)
+			return m.changed()
 		}
 	}
 	return nil
 }
 
+// handleInterrupt quits like handleQuit
 func handleInterrupt(m *model) tea.Cmd {
-	m.SaveState()
-	return tea.Quit
+	return handleQuit(m)
 }
 
 func handleTabNumber(m *model, i int) tea.Cmd {
@@ -413,14 +400,12 @@ func handleEnclosureNumber(m *model, i int) tea.Cmd {
 		return nil
 	}
 
-	selected := m.i.Item.Enclosures[i].URL
-	err := openInBrowser(selected)
-	if err != nil {
-		errorMessage := fmt.Sprintf("Error opening item, %q", err)
-		m.UpdateStatus(errorMessage)
-	}
+	enclosure := m.i.Item.Enclosures[i]
+	link := itemLink(m, m.i)
+	link.Url = enclosure.URL
+	link.Mime = enclosure.Type
 
-	return nil
+	return openLink(m, link)
 }
 
 func handleViewNext(m *model) tea.Cmd {
@@ -428,9 +413,10 @@ func handleViewNext(m *model) tea.Cmd {
 	if next != nil {
 		m.i = next
 		m.li.Select(index)
-		m.v.SetContent(wordwrap.String(next.Content(), 80))
+		setViewContent(m, next)
 		next.MarkRead()
 		rebuildItemsList(m)
+		return m.changed()
 	}
 	return nil
 }
@@ -440,9 +426,10 @@ func handleViewPrev(m *model) tea.Cmd {
 	if prev != nil {
 		m.i = prev
 		m.li.Select(index)
-		m.v.SetContent(wordwrap.String(prev.Content(), 80))
+		setViewContent(m, prev)
 		prev.MarkRead()
 		rebuildItemsList(m)
+		return m.changed()
 	}
 	return nil
 }
@@ -537,3 +524,234 @@ func handleViewHelp(m *model) tea.Cmd {
 func handleGoToStart(m *model) tea.Cmd {
 	return nil
 }
+
+func handleImportOPML(m *model) tea.Cmd {
+	return m.openPrompt(MsgImportOPML, "", importOPML)
+}
+
+func importOPML(m *model, path string) tea.Cmd {
+	if path == "" {
+		m.UpdateStatus(MsgCancelled)
+		return nil
+	}
+	if !m.saveForReload() {
+		return nil
+	}
+
+	f, err := os.Open(expandPath(path))
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+	defer f.Close()
+
+	subs, err := rss.ReadOPML(f)
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+
+	added, err := rss.ImportSubscriptions(m.l, subs)
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+
+	return reloadList(m, fmt.Sprintf("Imported %d of %d feeds", added, len(subs)))
+}
+
+func handleExportOPML(m *model) tea.Cmd {
+	return m.openPrompt(MsgExportOPML, "~/rssr.opml", exportOPML)
+}
+
+func exportOPML(m *model, path string) tea.Cmd {
+	if path == "" {
+		m.UpdateStatus(MsgCancelled)
+		return nil
+	}
+
+	path = expandPath(path)
+	f, err := os.Create(path)
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+	defer f.Close()
+
+	if err := m.l.WriteOPML(f, time.Now()); err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+
+	m.UpdateStatus(fmt.Sprintf("Exported feeds to %s", path))
+	return nil
+}
+
+func handleImportNewsboat(m *model) tea.Cmd {
+	return m.openPrompt(MsgImportNewsboat, rss.NewsboatUrlsPath(), importNewsboat)
+}
+
+func importNewsboat(m *model, path string) tea.Cmd {
+	if path == "" {
+		m.UpdateStatus(MsgCancelled)
+		return nil
+	}
+	if !m.saveForReload() {
+		return nil
+	}
+
+	f, err := os.Open(expandPath(path))
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+	defer f.Close()
+
+	subs, warnings, err := rss.ReadNewsboatUrls(f)
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+
+	added, err := rss.ImportSubscriptions(m.l, subs)
+	if err != nil {
+		m.UpdateStatus(err.Error())
+		return nil
+	}
+
+	status := fmt.Sprintf("Imported %d of %d feeds", added, len(subs))
+	if len(warnings) > 0 {
+		// Only room for the first one, the CLI lists them all
+		status = fmt.Sprintf("%s, %d lines skipped (%s)", status, len(warnings), warnings[0])
+	}
+
+	return reloadList(m, status)
+}
+
+func handleAddFeed(m *model) tea.Cmd {
+	return m.openPrompt(MsgAddFeedUrl, "", func(m *model, url string) tea.Cmd {
+		if url == "" {
+			m.UpdateStatus(MsgCancelled)
+			return nil
+		}
+
+		m.UpdateStatus(fmt.Sprintf("%s %s", MsgDiscovering, url))
+		return discoverCmd(url, addFeed)
+	})
+}
+
+func addFeed(m *model, url string) tea.Cmd {
+	category := activeTab(m.tabs, m.activeTab)
+	return m.openPrompt(MsgAddFeedCategory, category, func(m *model, category string) tea.Cmd {
+		feed, err := rss.AddSubscription(m.l, category, rss.FeedSettings{Url: url})
+		if err != nil {
+			m.UpdateStatus(err.Error())
+			return nil
+		}
+
+		m.UpdateStatus(fmt.Sprintf("Added %s to %s", url, category))
+		return tea.Batch(refreshTabs(m, category), updateFeedCmd(m, feed))
+	})
+}
+
+// handleFixFeed looks for the real feed of a failing feed, for when the
+// URL points to a web page.
+func handleFixFeed(m *model) tea.Cmd {
+	i, ok := m.lf.SelectedItem().(feedItem)
+	if !ok || i.rssFeed.Error == "" {
+		return nil
+	}
+
+	feed := i.rssFeed
+	m.UpdateStatus(fmt.Sprintf("%s %s", MsgDiscovering, feed.Url))
+	return discoverCmd(feed.Url, func(m *model, url string) tea.Cmd {
+		if url == feed.Url {
+			m.UpdateStatus(MsgFeedUrlIsFeed)
+			return nil
+		}
+
+		if err := rss.ReplaceSubscription(m.l, feed.Url, url); err != nil {
+			m.UpdateStatus(err.Error())
+			return nil
+		}
+
+		m.UpdateStatus(fmt.Sprintf("Feed changed to %s", url))
+		return tea.Batch(rebuildFeedList(m), updateFeedCmd(m, feed))
+	})
+}
+
+// handleDiscovered continues with the discovered feed, or asks which one
+// when a page has several.
+func handleDiscovered(m *model, msg discoveredMsg) tea.Cmd {
+	switch {
+	case errors.Is(msg.err, rss.ErrNoFeedFound):
+		m.UpdateStatus(fmt.Sprintf("%s at %s", rss.ErrNoFeedFound, msg.url))
+		return nil
+	case msg.err != nil:
+		// The site could not be checked, carry on with the URL as given
+		return msg.next(m, msg.url)
+	case len(msg.found) == 1:
+		return msg.next(m, msg.found[0].Url)
+	}
+
+	var urls []string
+	for _, f := range msg.found {
+		urls = append(urls, f.Url)
+	}
+
+	label := fmt.Sprintf(MsgChooseFeed, len(urls))
+	return m.openChoice(label, urls, msg.next)
+}
+
+func handleRemoveFeed(m *model) tea.Cmd {
+	i, ok := m.lf.SelectedItem().(feedItem)
+	if !ok {
+		return nil
+	}
+
+	feed := i.rssFeed
+	question := fmt.Sprintf("Remove %s?", feed.Name())
+	// Bookmarked items are removed with the feed
+	if n := feed.BookmarkCount(); n > 0 {
+		question = fmt.Sprintf("Remove %s and its %d bookmarks?", feed.Name(), n)
+	}
+	return m.openConfirm(question, func(m *model) tea.Cmd {
+		if err := rss.RemoveSubscription(m.l, feed.Url); err != nil {
+			m.UpdateStatus(err.Error())
+			return nil
+		}
+
+		m.UpdateStatus(fmt.Sprintf("Removed %s", feed.Name()))
+		return tea.Batch(refreshTabs(m, activeTab(m.tabs, m.activeTab)), m.changed())
+	})
+}
+
+func handleMoveFeed(m *model) tea.Cmd {
+	i, ok := m.lf.SelectedItem().(feedItem)
+	if !ok {
+		return nil
+	}
+
+	feed := i.rssFeed
+	return m.openPrompt(MsgMoveFeed, activeTab(m.tabs, m.activeTab), func(m *model, category string) tea.Cmd {
+		if err := rss.MoveSubscription(m.l, feed.Url, category); err != nil {
+			m.UpdateStatus(err.Error())
+			return nil
+		}
+
+		m.UpdateStatus(fmt.Sprintf("Moved %s to %s", feed.Name(), category))
+		return tea.Batch(refreshTabs(m, category), m.changed())
+	})
+}
+
+// refreshTabs rebuilds the tabs after categories changed and shows the tab,
+// or the nearest one when it no longer exists.
+func refreshTabs(m *model, tab string) tea.Cmd {
+	m.tabs = m.l.Categories()
+	if i := slices.Index(m.tabs, tab); i != -1 {
+		m.activeTab = i
+	} else if m.activeTab > len(m.tabs)-1 {
+		m.activeTab = max(len(m.tabs)-1, 0)
+	}
+	return rebuildFeedList(m)
+}
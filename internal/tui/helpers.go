@@ -1,90 +1,200 @@
 package tui
 
 import (
+	"context"
 	"fmt"
-	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"runtime"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
 	"charm.land/bubbles/v2/list"
 	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/colorprofile"
 	"github.com/emilosman/rssr/internal/rss"
 	"github.com/muesli/reflow/truncate"
+	"github.com/muesli/reflow/wordwrap"
 )
 
 type feedUpdatedMsg struct {
-	Feed *rss.RssFeed
-	Err  error
+	Feed        *rss.RssFeed
+	Err         error
+	NotModified bool
+	Cancelled   bool
+	Skipped     bool
+}
+
+type feedsDoneMsg struct {
+	ID        int
+	Auto      bool
+	Cancelled int
+	Err       error
+	// Single is set for the refresh of one feed
+	Single bool
+}
+
+// discoveredMsg carries the feeds found for url, next continues with the
+// chosen one.
+type discoveredMsg struct {
+	url   string
+	found []rss.DiscoveredFeed
+	err   error
+	next  func(m *model, url string) tea.Cmd
+}
+
+// openedMsg reports how an opener that took over the terminal exited
+type openedMsg struct {
+	err error
 }
 
-type feedsDoneMsg struct{}
 type statusClearMsg struct{}
+type autoRefreshMsg struct{}
 
-func updateAllFeedsCmd(m *model) tea.Cmd {
+// How often the auto refresh scheduler looks for feeds that are due
+const autoRefreshCheck = time.Minute
+
+func updateAllFeedsCmd(m *model, ctx context.Context, id int) tea.Cmd {
 	return func() tea.Msg {
-		results, err := m.l.UpdateAllFeeds()
+		results, err := m.l.UpdateAllFeedsContext(ctx, m.cfg.UpdateOptions())
 		if err != nil {
-			return feedUpdatedMsg{Feed: nil, Err: err}
+			return feedsDoneMsg{ID: id, Err: err}
 		}
 
-		go func() {
-			for res := range results {
-				m.prog.Send(feedUpdatedMsg{Feed: res.Feed, Err: res.Err})
-			}
-			m.prog.Send(feedsDoneMsg{})
-		}()
+		go sendFeedResults(m, results, feedsDoneMsg{ID: id})
 
 		return MsgUpdatingAllFeeds
 	}
 }
 
-func updateTabFeedsCmd(m *model) tea.Cmd {
+func updateTabFeedsCmd(m *model, ctx context.Context, id int) tea.Cmd {
 	return func() tea.Msg {
 		feeds, err := m.l.GetCategory(activeTab(m.tabs, m.activeTab))
 		if err != nil {
-			return feedUpdatedMsg{Feed: nil, Err: err}
+			return feedsDoneMsg{ID: id, Err: err}
 		}
 
-		results, err := rss.UpdateFeeds(feeds...)
+		results, err := rss.UpdateFeedsContext(ctx, m.cfg.UpdateOptions(), feeds...)
 		if err != nil {
-			return feedUpdatedMsg{Feed: nil, Err: err}
+			return feedsDoneMsg{ID: id, Err: err}
 		}
 
-		go func() {
-			for res := range results {
-				m.prog.Send(feedUpdatedMsg{Feed: res.Feed, Err: res.Err})
-			}
-			m.prog.Send(feedsDoneMsg{})
-		}()
+		go sendFeedResults(m, results, feedsDoneMsg{ID: id})
 
 		return MsgUpdatingAllFeeds
 	}
 }
 
 func updateFeedCmd(m *model, feed *rss.RssFeed) tea.Cmd {
+	m.refreshes++
+	ctx := m.refreshCtx
+
 	return func() tea.Msg {
-		results, err := rss.UpdateFeeds(feed)
+		opts := m.cfg.UpdateOptions()
+		opts.Force = true
+
+		results, err := rss.UpdateFeedsContext(ctx, opts, feed)
 		if err != nil {
-			return feedUpdatedMsg{Feed: nil, Err: err}
+			return feedsDoneMsg{Single: true, Err: err}
 		}
 
-		go func() {
-			for res := range results {
-				m.prog.Send(feedUpdatedMsg{Feed: res.Feed, Err: res.Err})
-			}
-		}()
+		go sendFeedResults(m, results, feedsDoneMsg{Single: true})
 
 		return fmt.Sprintf("%s %s", MsgUpdatingFeed, feed.Url)
 	}
 }
 
+func discoverCmd(url string, next func(m *model, url string) tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), rss.DefaultUpdateOptions.Timeout)
+		defer cancel()
+
+		found, err := rss.Discover(ctx, url)
+		return discoveredMsg{url: url, found: found, err: err, next: next}
+	}
+}
+
+// Forwards refresh results to the program, followed by the done message
+// with the number of feeds that were cancelled
+func sendFeedResults(m *model, results <-chan rss.FeedResult, done feedsDoneMsg) {
+	for res := range results {
+		if res.Cancelled {
+			done.Cancelled++
+		}
+		m.prog.Send(newFeedUpdatedMsg(res))
+	}
+	m.prog.Send(done)
+}
+
+func autoRefreshTick(m *model) tea.Cmd {
+	if m.cfg == nil || !m.cfg.Refresh.Enabled(m.l.Feeds...) {
+		return nil
+	}
+	m.autoRefreshTicking = true
+	return tea.Tick(autoRefreshCheck, func(time.Time) tea.Msg {
+		return autoRefreshMsg{}
+	})
+}
+
+// Refreshes the feeds whose auto refresh interval has passed. Runs alongside
+// manual refreshes, but only one auto refresh is in flight at a time.
+func autoRefreshCmd(m *model) tea.Cmd {
+	if m.autoRefreshing {
+		return nil
+	}
+
+	feeds := m.cfg.Refresh.DueFeeds(m.l.Feeds, time.Now())
+	if len(feeds) == 0 {
+		return nil
+	}
+
+	m.autoRefreshing = true
+	m.refreshes++
+	ctx := m.refreshCtx
+
+	return func() tea.Msg {
+		results, err := rss.UpdateFeedsContext(ctx, m.cfg.UpdateOptions(), feeds...)
+		if err != nil {
+			return feedsDoneMsg{Auto: true}
+		}
+
+		go sendFeedResults(m, results, feedsDoneMsg{Auto: true})
+
+		return nil
+	}
+}
+
+func newFeedUpdatedMsg(res rss.FeedResult) feedUpdatedMsg {
+	return feedUpdatedMsg{
+		Feed:        res.Feed,
+		Err:         res.Err,
+		NotModified: res.NotModified,
+		Cancelled:   res.Cancelled,
+		Skipped:     res.Skipped,
+	}
+}
+
+// Returns a cancellable context for a new refresh of several feeds
+func (m *model) startRefresh() (context.Context, int) {
+	if m.cancelRefresh != nil {
+		m.cancelRefresh()
+	}
+	ctx, cancel := context.WithCancel(m.refreshCtx)
+	m.cancelRefresh = cancel
+	m.refreshID++
+	m.refreshes++
+	return ctx, m.refreshID
+}
+
 // Builds the feed list and sets the items
 func rebuildFeedList(m *model) tea.Cmd {
 	items := buildFeedList(m)
-	m.lf.SetItems(items)
-	return nil
+	return m.lf.SetItems(items)
 }
 
 func rebuildItemsList(m *model) tea.Cmd {
@@ -95,6 +205,24 @@ func rebuildItemsList(m *model) tea.Cmd {
 	return nil
 }
 
+// Rebuilds the items list after the open feed was updated, keeping the
+// cursor on the same item when new items were added above it
+func refreshItemsList(m *model) tea.Cmd {
+	if m.li.FilterState().String() == "filter applied" {
+		return nil
+	}
+
+	selected, ok := m.li.SelectedItem().(rssListItem)
+	rebuildItemsList(m)
+
+	if ok {
+		if index := slices.Index(m.f.RssItems, selected.item); index != -1 {
+			m.li.Select(index)
+		}
+	}
+	return nil
+}
+
 // Builds the feed list
 func buildFeedList(m *model) []list.Item {
 	var listItems []list.Item
@@ -174,6 +302,108 @@ func buildItemsList(m *model) []list.Item {
 	return listItems
 }
 
+// Opens the link with the opener from config.yaml that matches it, or with
+// the browser. Openers that need the terminal get it until they exit
+func openLink(m *model, link rss.Link) tea.Cmd {
+	cmd, terminal, err := m.cfg.OpenCommand(link)
+	if err != nil {
+		m.UpdateStatus(fmt.Sprintf("%s, %q", ErrOpeningLink, err))
+		return nil
+	}
+
+	if terminal {
+		return tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return openedMsg{err: err}
+		})
+	}
+
+	if err := cmd.Start(); err != nil {
+		m.UpdateStatus(fmt.Sprintf("%s, %q", ErrOpeningLink, err))
+	}
+	return nil
+}
+
+func itemLink(m *model, item *rss.RssItem) rss.Link {
+	return rss.Link{Url: item.Link(), Title: item.Item.Title, Feed: m.l.FeedOf(item)}
+}
+
+// Renders the item into the viewport as set in config.yaml
+func setViewContent(m *model, item *rss.RssItem) {
+	cfg := m.cfg
+	if cfg == nil {
+		cfg = rss.DefaultConfig()
+	}
+
+	width := cfg.WrapWidth
+	if width == 0 {
+		width = m.v.Width()
+	}
+
+	content := item.RenderContent(rss.ContentOptions{
+		Markdown: cfg.RenderMarkdown,
+		Width:    width,
+		Style:    m.theme.Markdown,
+	})
+	m.v.SetContent(wordwrap.String(content, width))
+}
+
+// Applies the theme from config.yaml for the terminal background, to the
+// styles of rssr and of the bubbles components
+func (m *model) applyTheme() tea.Cmd {
+	m.theme = m.cfg.ResolveTheme(m.darkBackground)
+	setStyles(m.theme)
+
+	isDark := m.isDark()
+	m.lf.Styles = list.DefaultStyles(isDark)
+	m.li.Styles = list.DefaultStyles(isDark)
+	m.lf.Help.Styles = help.DefaultStyles(isDark)
+	m.li.Help.Styles = help.DefaultStyles(isDark)
+	m.vh.Styles = help.DefaultStyles(isDark)
+	m.lf.SetDelegate(newDelegate(&m.keys.feeds, isDark))
+	m.li.SetDelegate(newDelegate(&m.keys.items, isDark))
+
+	rebuildItemsList(m)
+	if m.i != nil {
+		setViewContent(m, m.i)
+	}
+
+	return tea.Batch(rebuildFeedList(m), m.colorProfileCmd())
+}
+
+func (m *model) isDark() bool {
+	switch m.theme.Base {
+	case rss.ThemeDark:
+		return true
+	case rss.ThemeLight:
+		return false
+	}
+	return m.darkBackground
+}
+
+// The mono theme drops the colors of the bubbles components as well, by
+// rendering without colors. Other themes restore the profile of the terminal
+func (m *model) colorProfileCmd() tea.Cmd {
+	profile := m.colorProfile
+	if m.theme.Base == rss.ThemeMono {
+		profile = colorprofile.ASCII
+	}
+	if profile == colorprofile.Unknown {
+		return nil
+	}
+	return func() tea.Msg {
+		return tea.ColorProfileMsg{Profile: profile}
+	}
+}
+
+// The help reads the keymap when shown, so it follows config reloads
+func newDelegate(keys *viewKeys, isDark bool) list.DefaultDelegate {
+	d := list.NewDefaultDelegate()
+	d.Styles = list.NewDefaultItemStyles(isDark)
+	d.ShortHelpFunc = func() []key.Binding { return keys.ShortHelp() }
+	d.FullHelpFunc = func() [][]key.Binding { return keys.FullHelp() }
+	return d
+}
+
 func renderedTabs(m *model) string {
 	var renderedTabs string
 	for i, tab := range m.tabs {
@@ -204,6 +434,9 @@ func renderedTitle(m *model) string {
 }
 
 func renderedStatus(m *model) string {
+	if m.prompt != nil {
+		return m.prompt.input.View()
+	}
 	return statusStyle.Render(m.status)
 }
 
@@ -217,30 +450,6 @@ func renderedItemTitle(m *model) string {
 	return itemTitleStyle.Render(m.i.Title())
 }
 
-func openInBrowser(raw string) error {
-	var cmd *exec.Cmd
-
-	parsed, err := url.ParseRequestURI(raw)
-	if err != nil {
-		return err
-	}
-
-	url := parsed.String()
-
-	switch runtime.GOOS {
-	case "darwin":
-		cmd = exec.Command("open", url)
-	case "linux":
-		cmd = exec.Command("xdg-open", url)
-	case "windows":
-		cmd = exec.Command("cmd", "/c", "start", "", url)
-	default:
-		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
-	}
-
-	return cmd.Start()
-}
-
 func (m *model) UpdateTitle(title string) {
 	m.title = title
 }
@@ -257,19 +466,48 @@ func (m *model) UpdateStatus(msg string) {
 	})
 }
 
-func (m *model) SaveState() error {
-	dataFilePath, err := rss.DataFilePath()
-	if err != nil {
-		return err
+// Loads config.yaml and the keymap it sets. The defaults are returned along
+// with the error when either is invalid.
+func loadConfig() (*rss.Config, keymap, error) {
+	cfg := rss.DefaultConfig()
+	dir, err := rss.ConfigFilePath()
+	if err == nil {
+		cfg, err = rss.LoadConfig(os.DirFS(dir))
 	}
 
-	f, err := os.Create(dataFilePath)
-	if err != nil {
-		return err
+	if err == nil {
+		keys, keysErr := newKeymap(cfg.Keys)
+		if keysErr == nil {
+			return cfg, keys, nil
+		}
+		cfg, err = rss.DefaultConfig(), keysErr
+	}
+
+	keys, _ := newKeymap(cfg.Keys)
+	return cfg, keys, err
+}
+
+// Returns the command that edits path, trying the editor from config.yaml,
+// then $VISUAL and $EDITOR
+func editorCommand(editor, path string) *exec.Cmd {
+	for _, e := range []string{editor, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if fields := strings.Fields(e); len(fields) > 0 {
+			return exec.Command(fields[0], append(fields[1:], path)...)
+		}
 	}
-	defer f.Close()
 
-	return m.l.Save(f, time.Now())
+	if runtime.GOOS == "windows" {
+		return exec.Command("notepad", path)
+	}
+	return exec.Command("nvim", path)
+}
+
+func (m *model) SaveState() error {
+	if err := rss.SaveList(m.l); err != nil {
+		return err
+	}
+	m.unsaved = false
+	return nil
 }
 
 func BuildApp() {
@@ -277,7 +515,31 @@ func BuildApp() {
 	p := tea.NewProgram(m)
 	m.prog = p
 
-	if _, err := p.Run(); err != nil {
+	// The program quits on SIGTERM by itself, SIGHUP is sent when the
+	// terminal closes
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		<-hup
+		p.Quit()
+	}()
+
+	_, err := p.Run()
+	signal.Stop(hup)
+
+	// Refreshes write to the feeds being saved, stop them first
+	m.stopRefreshes()
+	rss.WaitRefreshes(quitRefreshWait)
+
+	// Quitting from a signal skips handleQuit, save what is left
+	if saveErr := m.flush(); saveErr != nil {
+		fmt.Println(ErrSavingState+":", saveErr)
+	}
+	if m.l.Storage != nil {
+		m.l.Storage.Close()
+	}
+
+	if err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
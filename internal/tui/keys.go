@@ -0,0 +1,186 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"github.com/emilosman/rssr/internal/rss"
+)
+
+type keyHandler func(*model) tea.Cmd
+
+// action is a command that keys can be bound to in a view. Actions are
+// listed in the order the full help shows them.
+type action struct {
+	name    string
+	keys    []string
+	help    string
+	short   bool
+	handler keyHandler
+}
+
+var feedActions, itemActions, viewActions []action
+
+// The actions are set in init as their handlers reload the keymap
+func init() {
+	feedActions = []action{
+		{"add_feed", []string{"a"}, "add feed", false, handleAddFeed},
+		{"remove_feed", []string{"d"}, "remove feed", false, handleRemoveFeed},
+		{"move_feed", []string{"m"}, "move feed to tab", false, handleMoveFeed},
+		{"fix_feed", []string{"F"}, "find feed of failing URL", false, handleFixFeed},
+		{"prev_tab", []string{"left", "h"}, "previous tab", true, handlePrevTab},
+		{"next_tab", []string{"right", "l", "tab"}, "next tab", true, handleNextTab},
+		{"next_unread", []string{"n"}, "next unread feed", false, handleNextUnreadFeed},
+		{"prev_unread", []string{"p", "b"}, "previous unread feed", false, handlePrevUnreadFeed},
+		{"open_latest", []string{"o"}, "open latest item", true, handleOpenLatest},
+		{"open_website", []string{"O"}, "open website", false, handleOpenFeed},
+		{"view_feed", []string{"enter"}, "view feed", true, handleEnterFeed},
+		{"refresh", []string{"r"}, "refresh single feed", false, handleUpdateFeed},
+		{"refresh_tab", []string{"ctrl+r"}, "refresh tab", false, handleTabUpdate},
+		{"refresh_all", []string{"R"}, "refresh all feeds", true, handleUpdateAllFeeds},
+		{"cancel_refresh", []string{"ctrl+x"}, "cancel refresh", false, handleCancelRefresh},
+		{"mark_feed_read", []string{"A"}, "mark feed as read", false, handleMarkFeedRead},
+		{"mark_tab_read", []string{"ctrl+a"}, "mark tab as read", false, handleMarkTabAsRead},
+		{"bookmarks", []string{"B"}, "bookmarks list", false, handleViewBookmarks},
+		{"edit_urls", []string{"E"}, "edit URLs file", true, handleEdit},
+		{"edit_config", []string{"C"}, "edit config", false, handleEditConfig},
+		{"import_opml", []string{"I"}, "import OPML", false, handleImportOPML},
+		{"export_opml", []string{"X"}, "export OPML", false, handleExportOPML},
+		{"import_newsboat", []string{"N"}, "import newsboat urls", false, handleImportNewsboat},
+		{"quit", []string{"q", "esc"}, "quit", true, handleQuit},
+		{"interrupt", []string{"ctrl+c"}, "quit", false, handleInterrupt},
+	}
+
+	itemActions = []action{
+		{"toggle_read", []string{"a"}, "toggle read", true, handleToggleRead},
+		{"toggle_bookmark", []string{"c"}, "bookmark item", true, handleToggleBookmark},
+		{"open", []string{"o"}, "open item url", true, handleOpenItem},
+		{"back", []string{"b", "q", "esc"}, "back", true, handleBack},
+		{"refresh", []string{"r"}, "refresh feed", true, handleUpdateFeed},
+		{"mark_all_read", []string{"A"}, "mark all items read", true, handleMarkItemsRead},
+		{"view_item", []string{"enter"}, "preview item", true, handleViewItem},
+		{"next_unread", []string{"n"}, "next unread item", false, handleNextUnreadItem},
+		{"prev_unread", []string{"p"}, "previous unread item", false, handlePrevUnreadItem},
+		{"open_website", []string{"O"}, "open website", false, handleOpenFeed},
+		{"refresh_all", []string{"R"}, "refresh all feeds", false, handleUpdateAllFeeds},
+		{"cancel_refresh", []string{"ctrl+x"}, "cancel refresh", false, handleCancelRefresh},
+		{"bookmarks", []string{"B"}, "bookmarks list", false, handleViewBookmarks},
+		{"interrupt", []string{"ctrl+c"}, "quit", false, handleInterrupt},
+	}
+
+	viewActions = []action{
+		{"prev", []string{"left", "h"}, "previous article", true, handleViewPrev},
+		{"next", []string{"right", "l"}, "next article", true, handleViewNext},
+		{"toggle_read", []string{"a"}, "toggle read", true, handleToggleRead},
+		{"toggle_bookmark", []string{"c"}, "bookmark item", true, handleToggleBookmark},
+		{"open", []string{"o", "enter"}, "open website", true, handleOpenItem},
+		{"back", []string{"b", "q", "esc"}, "back", true, handleBack},
+		{"next_unread", []string{"n"}, "next unread item", false, handleNextUnreadItem},
+		{"prev_unread", []string{"p"}, "previous unread item", false, handlePrevUnreadItem},
+		{"go_to_start", []string{"g"}, "go to start", false, handleGoToStart},
+		{"bookmarks", []string{"B"}, "bookmarks list", false, handleViewBookmarks},
+		{"cancel_refresh", []string{"ctrl+x"}, "cancel refresh", false, handleCancelRefresh},
+		{"help", []string{"?"}, "toggle help", false, handleViewHelp},
+		{"interrupt", []string{"ctrl+c"}, "quit", false, handleInterrupt},
+	}
+}
+
+// keymap holds the active bindings of every view.
+type keymap struct {
+	feeds, items, view viewKeys
+}
+
+// viewKeys maps keys to handlers in one view and keeps the help built from
+// the same bindings.
+type viewKeys struct {
+	handlers map[string]keyHandler
+	short    []key.Binding
+	full     []key.Binding
+}
+
+// newKeymap binds the actions of every view, with the keys from config.yaml
+// replacing the defaults of the actions they list.
+func newKeymap(cfg rss.KeysConfig) (keymap, error) {
+	var problems []string
+
+	feeds, p := bindKeys("feeds", feedActions, cfg.Feeds, feedExtraHelp)
+	problems = append(problems, p...)
+	items, p := bindKeys("items", itemActions, cfg.Items, itemExtraHelp)
+	problems = append(problems, p...)
+	view, p := bindKeys("item", viewActions, cfg.Item, viewExtraHelp)
+	problems = append(problems, p...)
+
+	if len(problems) > 0 {
+		return keymap{}, fmt.Errorf("%w: %s", rss.ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return keymap{feeds: feeds, items: items, view: view}, nil
+}
+
+func bindKeys(view string, actions []action, overrides map[string]rss.KeyList, extra []key.Binding) (viewKeys, []string) {
+	var problems []string
+
+	for _, name := range slices.Sorted(maps.Keys(overrides)) {
+		if !slices.ContainsFunc(actions, func(a action) bool { return a.name == name }) {
+			problems = append(problems, fmt.Sprintf("keys.%s: unknown action %q", view, name))
+		}
+	}
+
+	v := viewKeys{handlers: make(map[string]keyHandler)}
+	boundTo := make(map[string]string)
+
+	for _, a := range actions {
+		keys := a.keys
+		if override, ok := overrides[a.name]; ok {
+			keys = nil
+			for _, k := range override {
+				keys = append(keys, normalizeKey(k))
+			}
+		}
+
+		for _, k := range keys {
+			if k == "" {
+				problems = append(problems, fmt.Sprintf("keys.%s.%s: empty key", view, a.name))
+				continue
+			}
+			if len(k) == 1 && unicode.IsDigit(rune(k[0])) {
+				problems = append(problems, fmt.Sprintf("keys.%s.%s: %q is reserved for numbers", view, a.name, k))
+				continue
+			}
+			if other, ok := boundTo[k]; ok {
+				problems = append(problems, fmt.Sprintf("keys.%s: %q is bound to both %s and %s", view, k, other, a.name))
+				continue
+			}
+			boundTo[k] = a.name
+			v.handlers[k] = a.handler
+		}
+
+		if len(keys) == 0 {
+			continue
+		}
+
+		label := keyLabel(keys)
+		b := key.NewBinding(key.WithKeys(keys...), key.WithHelp(label, a.help))
+		v.full = append(v.full, b)
+		if a.short {
+			v.short = append(v.short, b)
+		}
+	}
+
+	v.full = append(v.full, extra...)
+	return v, problems
+}
+
+// normalizeKey writes shift with a letter the way key presses report it,
+// so "shift+r" and "R" are the same key.
+func normalizeKey(k string) string {
+	k = strings.TrimSpace(k)
+	if letter, ok := strings.CutPrefix(k, "shift+"); ok && len(letter) == 1 {
+		return strings.ToUpper(letter)
+	}
+	return k
+}
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/emilosman/rssr/internal/rss"
+)
+
+func TestKeys(t *testing.T) {
+	t.Run("Should bind default keys without conflicts", func(t *testing.T) {
+		keys, err := newKeymap(rss.KeysConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		for _, k := range []string{"R", "enter", "ctrl+c", "tab"} {
+			if keys.feeds.handlers[k] == nil {
+				t.Errorf("Key %q should be bound in feeds view", k)
+			}
+		}
+
+		if len(keys.feeds.ShortHelp()) == 0 || len(keys.view.FullHelp()) == 0 {
+			t.Error("Help should be built from the bindings")
+		}
+	})
+
+	t.Run("Should replace default keys of action", func(t *testing.T) {
+		keys, err := newKeymap(rss.KeysConfig{
+			Items: map[string]rss.KeyList{"toggle_bookmark": {"x"}, "next_unread": {"shift+j", "down"}},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if keys.items.handlers["x"] == nil || keys.items.handlers["c"] != nil {
+			t.Error("toggle_bookmark should be bound to x only")
+		}
+
+		if keys.items.handlers["J"] == nil {
+			t.Error("shift+j should be bound as J")
+		}
+
+		if keys.view.handlers["c"] == nil {
+			t.Error("Other views should keep their keys")
+		}
+
+		var labels []string
+		for _, b := range keys.items.full {
+			labels = append(labels, b.Help().Key)
+		}
+		if !strings.Contains(strings.Join(labels, " "), "shift+j/down") {
+			t.Errorf("Help should show the new keys, got %v", labels)
+		}
+	})
+
+	t.Run("Should unbind action with no keys", func(t *testing.T) {
+		keys, err := newKeymap(rss.KeysConfig{Feeds: map[string]rss.KeyList{"remove_feed": {}}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if keys.feeds.handlers["d"] != nil {
+			t.Error("remove_feed should not be bound")
+		}
+	})
+
+	t.Run("Should bind cancel_refresh in every view", func(t *testing.T) {
+		keys, err := newKeymap(rss.KeysConfig{Items: map[string]rss.KeyList{"cancel_refresh": {"s"}}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %q", err)
+		}
+
+		if keys.feeds.handlers["ctrl+x"] == nil || keys.view.handlers["ctrl+x"] == nil {
+			t.Error("cancel_refresh should be bound to ctrl+x")
+		}
+		if keys.items.handlers["s"] == nil || keys.items.handlers["ctrl+x"] != nil {
+			t.Error("cancel_refresh should be bound to s only in items view")
+		}
+		if keys.items.handlers["esc"] == nil {
+			t.Error("esc should stay bound to back")
+		}
+
+		_, err = newKeymap(rss.KeysConfig{Feeds: map[string]rss.KeyList{"cancel_refresh": {"esc"}}})
+		if err == nil || !strings.Contains(err.Error(), "bound to both cancel_refresh and quit") {
+			t.Errorf("Expected a conflict with quit, got %q", err)
+		}
+	})
+
+	t.Run("Should report conflicts and unknown actions", func(t *testing.T) {
+		_, err := newKeymap(rss.KeysConfig{
+			Items: map[string]rss.KeyList{"toggle_bookmark": {"a"}},
+			Item:  map[string]rss.KeyList{"next_unred": {"j"}, "prev": {"1"}},
+		})
+		if !errors.Is(err, rss.ErrInvalidConfig) {
+			t.Fatalf("got %q want %q", err, rss.ErrInvalidConfig)
+		}
+
+		for _, want := range []string{
+			`keys.items: "a" is bound to both toggle_read and toggle_bookmark`,
+			`keys.item: unknown action "next_unred"`,
+			`keys.item.prev: "1" is reserved for numbers`,
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Error should contain %s, got %q", want, err)
+			}
+		}
+	})
+}
@@ -1,18 +1,38 @@
 package tui
 
 var (
-	MsgUpdatingAllFeeds = "Updating all feeds..."
-	MsgAllFeedsUpdated  = "All feeds updated"
-	MsgMarkItemRead     = "Marked as read"
-	MsgMarkItemUnread   = "Marked as unread"
-	MsgMarkFeedRead     = "Marked feed as read"
-	MsgMarkAllFeedsRead = "Marked all feeds as read"
-	MsgBookmarkAdded    = "Bookmark added"
-	MsgBookmarkRemoved  = "Bookmark removed"
-	MsgMakrTabAsRead    = "Marked all feeds in tab as read"
-	MsgUpdatingFeed     = "Updating feed"
-	MsgFeedUpdated      = "Feed updated"
-	MsgNoFeedsInList    = "No feeds in list. Press shift+e to edit URLs file"
-	ErrUpdatingFeed     = "Error updating feed"
-	ErrUpdatingFeeds    = "Error updating feeds"
+	MsgUpdatingAllFeeds  = "Updating all feeds..."
+	MsgAllFeedsUpdated   = "All feeds updated"
+	MsgAutoRefreshDone   = "Feeds refreshed in background"
+	MsgCancellingRefresh = "Cancelling refresh..."
+	MsgRefreshCancelled  = "Refresh cancelled"
+	MsgWaitForRefresh    = "Wait for the refresh to finish or cancel it"
+	MsgMarkItemRead      = "Marked as read"
+	MsgMarkItemUnread    = "Marked as unread"
+	MsgMarkFeedRead      = "Marked feed as read"
+	MsgMarkAllFeedsRead  = "Marked all feeds as read"
+	MsgBookmarkAdded     = "Bookmark added"
+	MsgBookmarkRemoved   = "Bookmark removed"
+	MsgMakrTabAsRead     = "Marked all feeds in tab as read"
+	MsgUpdatingFeed      = "Updating feed"
+	MsgFeedUpdated       = "Feed updated"
+	MsgFeedNotModified   = "No changes in"
+	MsgNoFeedsInList     = "No feeds in list. Press shift+e to edit URLs file"
+	MsgCancelled         = "Cancelled"
+	MsgImportOPML        = "Import OPML file: "
+	MsgExportOPML        = "Export OPML to: "
+	MsgImportNewsboat    = "Import newsboat urls file: "
+	MsgAddFeedUrl        = "Feed URL: "
+	MsgAddFeedCategory   = "Category: "
+	MsgMoveFeed          = "Move to category: "
+	MsgDiscovering       = "Looking for feeds at"
+	MsgChooseFeed        = "Feed (%d found, ctrl+n for next): "
+	MsgFeedUrlIsFeed     = "URL is already a feed"
+	MsgNotAnOption       = "Not one of the choices:"
+	MsgFixFeedHint       = "press shift+f to look for its feed"
+	MsgConfigReloaded    = "Config reloaded"
+	ErrOpeningLink       = "Error opening link"
+	ErrUpdatingFeed      = "Error updating feed"
+	ErrUpdatingFeeds     = "Error updating feeds"
+	ErrSavingState       = "Error saving read state"
 )
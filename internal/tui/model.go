@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -10,8 +12,8 @@ import (
 	"charm.land/bubbles/v2/list"
 	"charm.land/bubbles/v2/viewport"
 	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/colorprofile"
 	"github.com/emilosman/rssr/internal/rss"
-	"github.com/muesli/reflow/wordwrap"
 )
 
 type feedItem struct {
@@ -38,6 +40,9 @@ type model struct {
 	status     string
 	clearTimer *time.Timer
 	l          *rss.List
+	cfg        *rss.Config
+	keys       keymap
+	theme      rss.Theme
 	f          *rss.RssFeed
 	i          *rss.RssItem
 	lf         list.Model
@@ -46,6 +51,27 @@ type model struct {
 	vh         help.Model
 	tabs       []string
 	activeTab  int
+	prompt     *prompt
+
+	cancelRefresh  context.CancelFunc
+	refreshID      int
+	autoRefreshing bool
+	// Whether an auto refresh tick is scheduled
+	autoRefreshTicking bool
+	// Refreshes in flight, autosave waits for them
+	refreshes int
+	// Parent of every refresh, cancelled on exit
+	refreshCtx    context.Context
+	stopRefreshes context.CancelFunc
+
+	// Changes not saved yet, see changed
+	unsaved      bool
+	unsavedSince time.Time
+	saveGen      int
+
+	// Reported by the terminal, dark until it answers
+	darkBackground bool
+	colorProfile   colorprofile.Profile
 }
 
 func initialModel() *model {
@@ -53,26 +79,37 @@ func initialModel() *model {
 	if err != nil {
 		fmt.Println("Error opening config dir", err)
 	}
-	filesystem := os.DirFS(urlsFilePath)
-	l, err := rss.LoadList(filesystem)
-	t := l.Categories()
 
-	df := list.NewDefaultDelegate()
-	df.ShortHelpFunc = listShortHelp
-	df.FullHelpFunc = listFullHelp
+	cfg, keys, cfgErr := loadConfig()
 
-	di := list.NewDefaultDelegate()
-	di.ShortHelpFunc = itemsShortHelp
-	di.FullHelpFunc = itemsFullHelp
+	// Falls back to data.json when the storage does not open
+	storage, storageErr := rss.OpenStorage(cfg.Storage)
+	l, err := rss.LoadListFrom(os.DirFS(urlsFilePath), storage)
+	l.SortCategories = cfg.SortCategories
+	pruned := l.Prune(cfg.Retention, time.Now())
+	t := l.Categories()
 
 	m := &model{
 		l:         l,
-		lf:        list.New(nil, df, 0, 0),
-		li:        list.New(nil, di, 0, 0),
+		cfg:       cfg,
+		keys:      keys,
 		tabs:      t,
 		activeTab: 0,
 		v:         viewport.New(),
 		vh:        help.New(),
+
+		darkBackground: true,
+	}
+
+	m.refreshCtx, m.stopRefreshes = context.WithCancel(context.Background())
+
+	m.lf = list.New(nil, newDelegate(&m.keys.feeds, true), 0, 0)
+	m.li = list.New(nil, newDelegate(&m.keys.items, true), 0, 0)
+
+	// Saved with the next change, or on quit
+	if len(pruned) > 0 {
+		m.unsaved = true
+		m.unsavedSince = time.Now()
 	}
 
 	rebuildFeedList(m)
@@ -84,6 +121,14 @@ func initialModel() *model {
 	m.lf.SetShowStatusBar(false)
 	m.li.SetShowStatusBar(true)
 
+	if cfgErr != nil {
+		m.UpdateStatus(cfgErr.Error())
+	}
+
+	if storageErr != nil {
+		m.UpdateStatus(storageErr.Error())
+	}
+
 	if err != nil {
 		m.UpdateStatus(err.Error())
 	}
@@ -96,26 +141,82 @@ func initialModel() *model {
 }
 
 func (m *model) Init() tea.Cmd {
-	return nil
+	return tea.Batch(autoRefreshTick(m), m.applyTheme(), tea.RequestBackgroundColor)
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case feedUpdatedMsg:
-		if msg.Err != nil {
+		if msg.Cancelled || msg.Skipped {
+			return m, rebuildFeedList(m)
+		}
+		if errors.Is(msg.Err, rss.ErrHTMLPage) {
+			m.UpdateStatus(fmt.Sprintf("%s is a web page, %s", msg.Feed.Url, MsgFixFeedHint))
+		} else if msg.Err != nil {
 			m.UpdateStatus(fmt.Sprintf("Error updating: %v", msg.Err))
+		} else if msg.NotModified {
+			m.UpdateStatus(fmt.Sprintf("%s %s", MsgFeedNotModified, msg.Feed.Title()))
 		} else {
 			m.UpdateStatus(fmt.Sprintf("Updated %s", msg.Feed.Title()))
 		}
-		rebuildFeedList(m)
-		return m, nil
+		if m.f != nil && m.f == msg.Feed {
+			refreshItemsList(m)
+		}
+		return m, tea.Batch(rebuildFeedList(m), m.changed())
 	case feedsDoneMsg:
-		m.UpdateStatus(MsgAllFeedsUpdated)
+		m.refreshes--
+		save := m.resumeAutosave()
+		if msg.Auto {
+			m.autoRefreshing = false
+			m.UpdateStatus(MsgAutoRefreshDone)
+			return m, save
+		}
+		if msg.ID == m.refreshID && !msg.Single {
+			m.cancelRefresh = nil
+		}
+		switch {
+		case msg.Err != nil:
+			m.UpdateStatus(fmt.Sprintf("Error updating: %v", msg.Err))
+		case msg.Single:
+			// Its feedUpdatedMsg showed how it went
+		case msg.Cancelled > 0:
+			m.UpdateStatus(fmt.Sprintf("%s, %d feeds not updated", MsgRefreshCancelled, msg.Cancelled))
+		default:
+			m.UpdateStatus(MsgAllFeedsUpdated)
+		}
+		return m, save
+	case autosaveMsg:
+		return m, handleAutosave(m, msg)
+	case autoRefreshMsg:
+		m.autoRefreshTicking = false
+		return m, tea.Batch(autoRefreshCmd(m), autoRefreshTick(m))
+	case discoveredMsg:
+		return m, handleDiscovered(m, msg)
+	case tea.BackgroundColorMsg:
+		m.darkBackground = msg.IsDark()
+		return m, m.applyTheme()
+	case tea.ColorProfileMsg:
+		// Keep the profile of the terminal to restore it after mono
+		if m.theme.Base != rss.ThemeMono {
+			m.colorProfile = msg.Profile
+		} else if msg.Profile != colorprofile.ASCII {
+			m.colorProfile = msg.Profile
+			return m, m.colorProfileCmd()
+		}
+		return m, nil
+	case openedMsg:
+		if msg.err != nil {
+			m.UpdateStatus(fmt.Sprintf("%s, %q", ErrOpeningLink, msg.err))
+		}
 		return m, nil
 	case statusClearMsg:
 		m.status = ""
 		return m, nil
 	case tea.KeyPressMsg:
+		if m.prompt != nil {
+			return m, handlePromptKey(m, msg)
+		}
+
 		var handlers map[string]keyHandler
 		lfState := m.lf.FilterState().String()
 		liState := m.li.FilterState().String()
@@ -126,17 +227,17 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		switch {
 		case m.i != nil:
-			handlers = viewKeyHandlers
+			handlers = m.keys.view.handlers
 			if i, err := strconv.Atoi(msg.String()); err == nil {
 				return m, handleEnclosureNumber(m, i)
 			}
 		case m.f != nil:
-			handlers = itemKeyHandlers
+			handlers = m.keys.items.handlers
 			if i, err := strconv.Atoi(msg.String()); err == nil {
 				return m, handleItemNumber(m, i)
 			}
 		default:
-			handlers = feedKeyHandlers
+			handlers = m.keys.feeds.handlers
 			if i, err := strconv.Atoi(msg.String()); err == nil {
 				return m, handleTabNumber(m, i)
 			}
@@ -156,12 +257,17 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.v.SetHeight(msg.Height - itemTopBarHeigh)
 
 		if m.i != nil {
-			m.v.SetContent(wordwrap.String(m.i.Content(), 80))
+			setViewContent(m, m.i)
 		}
 	}
 
 	var cmd tea.Cmd
 
+	if m.prompt != nil {
+		m.prompt.input, cmd = m.prompt.input.Update(msg)
+		return m, cmd
+	}
+
 	switch {
 	case m.i != nil:
 		m.v, cmd = m.v.Update(msg)
@@ -0,0 +1,126 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+)
+
+// prompt asks for a line of input in place of the status line. A confirm
+// prompt takes a single y/n key instead.
+type prompt struct {
+	input    textinput.Model
+	confirm  bool
+	onSubmit func(m *model, value string) tea.Cmd
+}
+
+func (m *model) openPrompt(label, value string, onSubmit func(m *model, value string) tea.Cmd) tea.Cmd {
+	input := m.newInput(label)
+	input.SetValue(value)
+	input.CursorEnd()
+
+	m.prompt = &prompt{input: input, onSubmit: onSubmit}
+	return m.prompt.input.Focus()
+}
+
+// openChoice asks for one of the options. The common start of the options
+// is filled in so all of them are suggested, enter takes the suggestion. An
+// empty value cancels, anything else that is not an option is rejected.
+func (m *model) openChoice(label string, options []string, onSubmit func(m *model, value string) tea.Cmd) tea.Cmd {
+	prefix := options[0]
+	for _, o := range options[1:] {
+		for !strings.HasPrefix(o, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix == "" {
+		prefix = options[0]
+	}
+
+	choose := func(m *model, value string) tea.Cmd {
+		if value == "" {
+			m.UpdateStatus(MsgCancelled)
+			return nil
+		}
+		if !slices.Contains(options, value) {
+			m.UpdateStatus(fmt.Sprintf("%s %s", MsgNotAnOption, value))
+			return nil
+		}
+		return onSubmit(m, value)
+	}
+
+	cmd := m.openPrompt(label, prefix, choose)
+	m.prompt.input.ShowSuggestions = true
+	m.prompt.input.SetSuggestions(options)
+	return cmd
+}
+
+// openConfirm asks a yes/no question, onYes runs on "y"
+func (m *model) openConfirm(question string, onYes func(m *model) tea.Cmd) tea.Cmd {
+	input := m.newInput(question + " (y/N) ")
+
+	m.prompt = &prompt{
+		input:   input,
+		confirm: true,
+		onSubmit: func(m *model, value string) tea.Cmd {
+			return onYes(m)
+		},
+	}
+	return nil
+}
+
+func (m *model) newInput(label string) textinput.Model {
+	input := textinput.New()
+	input.Prompt = label
+	input.SetStyles(textinput.DefaultStyles(m.isDark()))
+	return input
+}
+
+func handlePromptKey(m *model, msg tea.KeyPressMsg) tea.Cmd {
+	if m.prompt.confirm {
+		p := m.prompt
+		m.prompt = nil
+		if msg.String() == "y" || msg.String() == "Y" {
+			return p.onSubmit(m, "")
+		}
+		m.UpdateStatus(MsgCancelled)
+		return nil
+	}
+
+	switch msg.String() {
+	case "enter":
+		p := m.prompt
+		m.prompt = nil
+		value := p.input.Value()
+		if suggestion := p.input.CurrentSuggestion(); p.input.ShowSuggestions && suggestion != "" {
+			value = suggestion
+		}
+		return p.onSubmit(m, strings.TrimSpace(value))
+	case "esc", "ctrl+c":
+		m.prompt = nil
+		m.UpdateStatus(MsgCancelled)
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.prompt.input, cmd = m.prompt.input.Update(msg)
+	return cmd
+}
+
+// expandPath expands a leading ~ to the home directory
+func expandPath(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
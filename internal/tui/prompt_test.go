@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func TestPrompt(t *testing.T) {
+	options := []string{"https://example.com/feed.xml", "https://example.com/comments.xml"}
+	enter := tea.KeyPressMsg{Code: tea.KeyEnter}
+
+	choose := func(t *testing.T, value string) (*model, string) {
+		m := &model{}
+		t.Cleanup(func() {
+			if m.clearTimer != nil {
+				m.clearTimer.Stop()
+			}
+		})
+
+		var chosen string
+		m.openChoice(MsgChooseFeed, options, func(m *model, value string) tea.Cmd {
+			chosen = value
+			return nil
+		})
+		// Typed, so the suggestions follow the value
+		m.prompt.input.SetValue("")
+		handlePromptKey(m, tea.KeyPressMsg{Code: tea.KeyBackspace})
+		for _, r := range value {
+			handlePromptKey(m, tea.KeyPressMsg{Code: r, Text: string(r)})
+		}
+		handlePromptKey(m, enter)
+		return m, chosen
+	}
+
+	t.Run("Should submit one of the options", func(t *testing.T) {
+		if _, chosen := choose(t, options[1]); chosen != options[1] {
+			t.Errorf("got %q, want %q", chosen, options[1])
+		}
+	})
+
+	t.Run("Should cancel an empty choice", func(t *testing.T) {
+		m, chosen := choose(t, "")
+		if chosen != "" || m.status != MsgCancelled {
+			t.Errorf("got %q with status %q", chosen, m.status)
+		}
+	})
+
+	t.Run("Should reject a value that is not an option", func(t *testing.T) {
+		m, chosen := choose(t, "https://example.org/other.xml")
+		if chosen != "" || !strings.HasPrefix(m.status, MsgNotAnOption) {
+			t.Errorf("got %q with status %q", chosen, m.status)
+		}
+	})
+}
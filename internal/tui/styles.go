@@ -1,57 +1,86 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/emilosman/rssr/internal/rss"
+)
 
 var (
+	unreadStyle            lipgloss.Style
+	bookmarkStyle          lipgloss.Style
+	errorStyle             lipgloss.Style
+	titleStyle             lipgloss.Style
+	itemTitleStyle         lipgloss.Style
+	bookmarkItemTitleStyle lipgloss.Style
+	unreadItemTitleStyle   lipgloss.Style
+	statusStyle            lipgloss.Style
+	activeTabStyle         lipgloss.Style
+	unreadTabStyle         lipgloss.Style
+	inactiveTabStyle       lipgloss.Style
+
+	contentStyle = lipgloss.NewStyle().
+			Margin(0, 0, 0, 1)
+
+	helpStyle = lipgloss.NewStyle().
+			Margin(0, 0, 0, 2)
+)
+
+func init() {
+	setStyles(rss.DarkTheme)
+}
+
+// setStyles builds the styles from the colors of the theme.
+func setStyles(t rss.Theme) {
 	unreadStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("2")) // green
+		Foreground(color(t.Unread))
 
 	bookmarkStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("11")) // yellow
+		Foreground(color(t.Bookmark))
 
 	errorStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("9")) // red
+		Foreground(color(t.Error))
 
 	titleStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("13")). // magenta
+		Foreground(color(t.Title)).
 		Margin(0, 0, 0, 1)
 
 	itemTitleStyle = lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("13")).
+		Foreground(color(t.Title)).
 		Margin(0, 0, 0, 1)
 
 	bookmarkItemTitleStyle = lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("11")).
+		Foreground(color(t.Bookmark)).
 		Margin(0, 0, 0, 1)
 
 	unreadItemTitleStyle = lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("2")).
+		Foreground(color(t.Unread)).
 		Margin(1, 0, 0, 1)
 
 	statusStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("8")) // gray
-
-	contentStyle = lipgloss.NewStyle().
-		Margin(0, 0, 0, 1)
-
-	helpStyle = lipgloss.NewStyle().
-		Margin(0, 0, 0, 2)
+		Foreground(color(t.Status))
 
 	activeTabStyle = lipgloss.NewStyle().
 		Bold(true).
 		Underline(true).
-		Foreground(lipgloss.Color("13")).
+		Foreground(color(t.Title)).
 		Margin(0, 1, 0, 1)
 
 	unreadTabStyle = lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("2")).
+		Foreground(color(t.Unread)).
 		Margin(0, 1, 0, 1)
 
 	inactiveTabStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("7")). // light gray/white
+		Foreground(color(t.Tab)).
 		Margin(0, 1, 0, 1)
-)
+}
+
+func color(c string) lipgloss.TerminalColor {
+	if c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
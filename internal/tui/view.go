@@ -14,7 +14,7 @@ func (m *model) View() tea.View {
 		itemTitle := renderedItemTitle(m)
 		status := renderedStatus(m)
 		content := contentStyle.Render(m.v.View())
-		help := helpStyle.Render(m.vh.View(viewKeyMap{}))
+		help := helpStyle.Render(m.vh.View(m.keys.view))
 		view := lipgloss.JoinVertical(lipgloss.Left, feedTitle, itemTitle, status, content, help)
 		v.SetContent(view)
 		return v